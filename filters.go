@@ -2,19 +2,18 @@ package pongo2addons
 
 import (
 	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
 	"math/rand"
-	"regexp"
+	"reflect"
 	"strings"
 	"time"
 	"unicode/utf8"
 
-	"github.com/extemporalgenome/slug"
 	"github.com/flosch/go-humanize"
 	"github.com/flosch/pongo2/v6"
-	"github.com/russross/blackfriday/v2"
+	"github.com/rivo/uniseg"
 )
 
 func init() {
@@ -22,13 +21,28 @@ func init() {
 
 	// Regulars
 	pongo2.RegisterFilter("slugify", filterSlugify)
+	pongo2.RegisterFilter("unique_slug", filterUniqueSlug)
+	pongo2.RegisterFilter("camelcase", filterCamelcase)
+	pongo2.RegisterFilter("snake_case", filterSnakeCase)
+	pongo2.RegisterFilter("kebab_case", filterKebabCase)
+	pongo2.RegisterFilter("title_case", filterTitleCase)
+	pongo2.RegisterFilter("swapcase", filterSwapcase)
+	pongo2.RegisterFilter("transliterate", filterTransliterate)
+	pongo2.RegisterFilter("normalize", filterNormalize)
 	pongo2.RegisterFilter("filesizeformat", filterFilesizeformat)
 	pongo2.RegisterFilter("truncatesentences", filterTruncatesentences)
 	pongo2.RegisterFilter("truncatesentences_html", filterTruncatesentencesHTML)
 	pongo2.RegisterFilter("random", filterRandom)
 
+	// Extended versions of pongo2 builtins
+	pongo2.RegisterFilter("truncatechars_html_ext", filterTruncatecharsHTML)
+	pongo2.RegisterFilter("truncatewords_html_ext", filterTruncatewordsHTML)
+
 	// Markup
 	pongo2.RegisterFilter("markdown", filterMarkdown)
+	pongo2.RegisterFilter("markdown_frontmatter", filterMarkdownFrontMatter)
+	pongo2.RegisterFilter("markdown_text", filterMarkdownText)
+	pongo2.RegisterFilter("highlight", filterHighlight)
 
 	// Humanize
 	pongo2.RegisterFilter("timeuntil", filterTimeuntilTimesince)
@@ -46,6 +60,11 @@ func init() {
 	// Halpers
 	// prints error as error.Error()
 	pongo2.RegisterFilter("printerror", filterPrintError)
+	// error introspection: wrapped errors, sentinel errors, types and fields
+	pongo2.RegisterFilter("error_chain", filterErrorChain)
+	pongo2.RegisterFilter("error_is", filterErrorIs)
+	pongo2.RegisterFilter("error_type", filterErrorType)
+	pongo2.RegisterFilter("error_field", filterErrorField)
 
 	// break line each N symbols
 	pongo2.RegisterFilter("solidlinebreaksbr", filterSolidLineBreaksBR)
@@ -55,194 +74,82 @@ func init() {
 	// range integers for 1 to N
 	pongo2.RegisterFilter("range", filterRange)
 
-	// range integers for 1 to N
+	// Serialization
 	pongo2.RegisterFilter("json", filterJSON)
+	pongo2.RegisterFilter("to_yaml", filterToYAML)
+	pongo2.RegisterFilter("to_toml", filterToTOML)
+	pongo2.RegisterFilter("to_xml", filterToXML)
+	pongo2.RegisterFilter("urlencode_map", filterURLEncodeMap)
 
-	// range integers for 1 to N
+	// Join
 	pongo2.RegisterFilter("joinBr", filterJoinBr)
-}
-
-func filterMarkdown(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
-	return pongo2.AsSafeValue(string(blackfriday.Run([]byte(in.String())))), nil
-}
-
-func filterSlugify(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
-	return pongo2.AsValue(slug.Slug(in.String())), nil
+	pongo2.RegisterFilter("join_html", filterJoinHTML)
+	pongo2.RegisterFilter("join_natural", filterJoinNatural)
+	pongo2.RegisterFilter("join_attr", filterJoinAttr)
+
+	// Collections
+	pongo2.RegisterFilter("sort_by", filterSortBy)
+	pongo2.RegisterFilter("group_by", filterGroupBy)
+	pongo2.RegisterFilter("unique", filterUnique)
+	pongo2.RegisterFilter("chunk", filterChunk)
+	pongo2.RegisterFilter("zip", filterZip)
+	pongo2.RegisterFilter("flatten", filterFlatten)
+	pongo2.RegisterFilter("pluck", filterPluck)
+	pongo2.RegisterFilter("where", filterWhere)
+	pongo2.RegisterFilter("sum", filterSum)
+	pongo2.RegisterFilter("min", filterMin)
+	pongo2.RegisterFilter("max", filterMax)
+	pongo2.RegisterFilter("avg", filterAvg)
+
+	// Pagination
+	pongo2.RegisterFilter("paginate", filterPaginate)
+
+	// Text
+	pongo2.RegisterFilter("excerpt", filterExcerpt)
+	pongo2.RegisterFilter("readingtime", filterReadingTime)
+	pongo2.RegisterFilter("wordwrap_width", filterWordwrapWidth)
+	pongo2.RegisterFilter("soft_hyphenate", filterSoftHyphenate)
+
+	// Regular expressions
+	pongo2.RegisterFilter("regex_replace", filterRegexReplace)
+	pongo2.RegisterFilter("regex_match", filterRegexMatch)
+	pongo2.RegisterFilter("regex_find_all", filterRegexFindAll)
+	pongo2.RegisterFilter("regex_split", filterRegexSplit)
+
+	// selects values from nested data by JSONPath-like expression
+	pongo2.RegisterFilter("query", filterQuery)
 }
 
 func filterFilesizeformat(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
 	return pongo2.AsValue(humanize.IBytes(uint64(in.Integer()))), nil
 }
 
-var filterTruncatesentencesRe = regexp.MustCompile(`(?U:.*[\w]{3,}.*([\d][\.!?][\D]|[\D][\.!?][\s]|[\n$]))`)
-
 func filterTruncatesentences(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
-	count := param.Integer()
+	count, lang := sentencesParams(param)
 	if count <= 0 {
 		return pongo2.AsValue(""), nil
 	}
-	sentencens := filterTruncatesentencesRe.FindAllString(strings.TrimSpace(in.String()), -1)
-	return pongo2.AsValue(strings.TrimSpace(strings.Join(sentencens[:min(count, len(sentencens))], ""))), nil
-}
-
-// Taken from pongo2/filters_builtin.go
-func filterTruncateHTMLHelper(value string, newOutput *bytes.Buffer, cond func() bool, fn func(c rune, s int, idx int) int, finalize func()) {
-	vLen := len(value)
-	tagStack := make([]string, 0)
-	idx := 0
-
-	for idx < vLen && !cond() {
-		c, s := utf8.DecodeRuneInString(value[idx:])
-		if c == utf8.RuneError {
-			idx += s
-			continue
-		}
-
-		if c == '<' {
-			newOutput.WriteRune(c)
-			idx += s // consume "<"
-
-			if idx+1 < vLen {
-				if value[idx] == '/' {
-					// Close tag
-
-					newOutput.WriteString("/")
-
-					tag := ""
-					idx++ // consume "/"
-
-					for idx < vLen {
-						c2, size2 := utf8.DecodeRuneInString(value[idx:])
-						if c2 == utf8.RuneError {
-							idx += size2
-							continue
-						}
-
-						// End of tag found
-						if c2 == '>' {
-							idx++ // consume ">"
-							break
-						}
-						tag += string(c2)
-						idx += size2
-					}
-
-					if len(tagStack) > 0 {
-						// Ideally, the close tag is TOP of tag stack
-						// In malformed HTML, it must not be, so iterate through the stack and remove the tag
-						for i := len(tagStack) - 1; i >= 0; i-- {
-							if tagStack[i] == tag {
-								// Found the tag
-								tagStack[i] = tagStack[len(tagStack)-1]
-								tagStack = tagStack[:len(tagStack)-1]
-								break
-							}
-						}
-					}
-
-					newOutput.WriteString(tag)
-					newOutput.WriteString(">")
-				} else {
-					// Open tag
-
-					tag := ""
-
-					params := false
-					for idx < vLen {
-						c2, size2 := utf8.DecodeRuneInString(value[idx:])
-						if c2 == utf8.RuneError {
-							idx += size2
-							continue
-						}
-
-						newOutput.WriteRune(c2)
-
-						// End of tag found
-						if c2 == '>' {
-							idx++ // consume ">"
-							break
-						}
-
-						if !params {
-							if c2 == ' ' {
-								params = true
-							} else {
-								tag += string(c2)
-							}
-						}
-
-						idx += size2
-					}
-
-					// Add tag to stack
-					tagStack = append(tagStack, tag)
-				}
-			}
-		} else {
-			idx = fn(c, s, idx)
-		}
-	}
 
-	finalize()
+	text := []rune(strings.TrimSpace(in.String()))
+	cut, _ := sentencesCut(text, count, lang)
 
-	for i := len(tagStack) - 1; i >= 0; i-- {
-		tag := tagStack[i]
-		// Close everything from the regular tag stack
-		newOutput.WriteString(fmt.Sprintf("</%s>", tag))
-	}
+	return pongo2.AsValue(strings.TrimSpace(string(text[:cut]))), nil
 }
 
 func filterTruncatesentencesHTML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
-	count := param.Integer()
+	count, lang := sentencesParams(param)
 	if count <= 0 {
-		return pongo2.AsValue(""), nil
+		return pongo2.AsSafeValue(""), nil
 	}
 
 	value := in.String()
-	newLen := max(param.Integer(), 0)
-
-	newOutput := bytes.NewBuffer(nil)
-
-	sentencefilter := 0
-
-	filterTruncateHTMLHelper(value, newOutput, func() bool {
-		return sentencefilter >= newLen
-	}, func(_ rune, _ int, idx int) int {
-		// Get next word
-		wordFound := false
-
-		for idx < len(value) {
-			c2, size2 := utf8.DecodeRuneInString(value[idx:])
-			if c2 == utf8.RuneError {
-				idx += size2
-				continue
-			}
-
-			if c2 == '<' {
-				// HTML tag start, don't consume it
-				return idx
-			}
-
-			newOutput.WriteRune(c2)
-			idx += size2
-
-			if (c2 == '.' && !(idx+1 < len(value) && value[idx+1] >= '0' && value[idx+1] <= '9')) ||
-				c2 == '!' || c2 == '?' || c2 == '\n' {
-				// Sentence ends here, stop capturing it now
-				break
-			} else {
-				wordFound = true
-			}
-		}
-
-		if wordFound {
-			sentencefilter++
-		}
-
-		return idx
-	}, func() {})
+	text, offsets := htmlSentenceText(value)
+	cut, truncated := sentencesCut(text, count, lang)
+	if !truncated {
+		return pongo2.AsSafeValue(value), nil
+	}
 
-	return pongo2.AsSafeValue(newOutput.String()), nil
+	return pongo2.AsSafeValue(truncateHTMLAt(value, offsets[cut], "")), nil
 }
 
 func filterRandom(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
@@ -345,30 +252,81 @@ func filterIMultiply(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pon
 	return pongo2.AsValue(in.Integer() * param.Integer()), nil
 }
 
-func filterPrintError(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+// filterPrintError prints error.Error() for errors and the value itself otherwise. Nil errors,
+// typed nil pointers included, print the parameter: {{ err|printerror:"no error" }}. Other nil
+// values like nil slices or maps print nothing.
+func filterPrintError(in *pongo2.Value, param *pongo2.Value) (out *pongo2.Value, perr *pongo2.Error) {
 	i := in.Interface()
-	switch i.(type) {
+	if isNilError(i) {
+		return pongo2.AsValue(param.String()), nil
+	}
+	if isNilValue(i) {
+		return pongo2.AsValue(""), nil
+	}
+
+	switch e := i.(type) {
 	case error:
-		return pongo2.AsValue(i.(error).Error()), nil
+		defer func() {
+			if r := recover(); r != nil {
+				out, perr = nil, &pongo2.Error{
+					Sender:    "filter:printerror",
+					OrigError: fmt.Errorf("%T.Error() panicked: %v", e, r),
+				}
+			}
+		}()
+		return pongo2.AsValue(e.Error()), nil
 	}
 
 	return pongo2.AsValue(i), nil
 }
 
-func filterJSON(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
-	js, err := json.Marshal(in.Interface())
-	if err != nil {
-		return nil, &pongo2.Error{
-			Sender:    "filter:json",
-			OrigError: err,
-		}
+// isNilError reports nil and the typed nil pointers implementing error. Other nil values
+// like nil slices or maps are not errors.
+func isNilError(i interface{}) bool {
+	if i == nil {
+		return true
+	}
+	if _, ok := i.(error); !ok {
+		return false
 	}
 
-	return pongo2.AsValue(string(js)), nil
+	v := reflect.ValueOf(i)
+	return v.Kind() == reflect.Ptr && v.IsNil()
 }
 
+// isNilValue reports the nil values of pointers, maps, slices, channels and funcs
+// wrapped into an interface.
+func isNilValue(i interface{}) bool {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return v.IsNil()
+	}
+
+	return false
+}
+
+// isSafeValue reports whether the value is marked safe, like the results of the safe filter.
+// pongo2 doesn't export the flag.
+func isSafeValue(v *pongo2.Value) bool {
+	safe := reflect.ValueOf(v).Elem().FieldByName("safe")
+	return safe.IsValid() && safe.Kind() == reflect.Bool && safe.Bool()
+}
+
+// filterSolidLineBreaksBR inserts the breaker ("<br />" by default) each N characters:
+//
+//	{{ text|solidlinebreaksbr:"20" }}
+//	{{ text|solidlinebreaksbr:"20,<wbr>,words" }}
+//	{{ post.Body|solidlinebreaksbr:"20,<wbr>,html" }}
+//
+// Characters are grapheme clusters, so emoji sequences, flags and combining accents are never
+// split. With "words" only the words longer than N are broken. The input is escaped as text;
+// with "html", or if the input is a safe value, its tags are kept and not counted.
 func filterSolidLineBreaksBR(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
 	eachBr := pongoParam(param, 0).Integer()
+	if param.IsNumber() {
+		eachBr = param.Integer()
+	}
 	line := in.String()
 
 	if len(line) == 0 || eachBr < 2 {
@@ -379,17 +337,61 @@ func filterSolidLineBreaksBR(in *pongo2.Value, param *pongo2.Value) (*pongo2.Val
 	if breaker == "" {
 		breaker = "<br />"
 	}
+	// tags are kept only with the html option or in safe values, like the output of other filters
+	words, markup := false, isSafeValue(in)
+	if !param.IsNumber() {
+		options := strings.Split(param.String(), ",")
+		for _, option := range options[min(len(options), 2):] {
+			switch strings.TrimSpace(option) {
+			case "words":
+				words = true
+			case "html":
+				markup = true
+			}
+		}
+	}
 
 	var b bytes.Buffer
-	data := []rune(line)
-	for i := 0; i < len(data); i++ {
-		b.WriteString(string(data[i]))
-		if (i+1)%eachBr == 0 && i != len(data)-1 {
-			b.WriteString(breaker)
+	var text []rune
+	count := 0
+
+	flush := func() {
+		g := uniseg.NewGraphemes(string(text))
+		for g.Next() {
+			cluster := g.Str()
+			if words {
+				r, _ := utf8.DecodeRuneInString(cluster)
+				if !isWordRune(r) {
+					count = 0
+					b.WriteString(html.EscapeString(cluster))
+					continue
+				}
+			}
+			if count > 0 && count%eachBr == 0 {
+				b.WriteString(breaker)
+			}
+			count++
+			b.WriteString(html.EscapeString(cluster))
 		}
+		text = text[:0]
 	}
 
-	return pongo2.AsValue(b.String()), nil
+	if !markup {
+		text = []rune(line)
+		flush()
+		return pongo2.AsSafeValue(b.String()), nil
+	}
+
+	walkHTML(line, func(raw string, _ string, _ []string) {
+		flush()
+		b.WriteString(raw)
+	}, func(r rune, _ string) bool {
+		text = append(text, r)
+		return true
+	})
+	flush()
+
+	return pongo2.AsSafeValue(b.String()), nil
 }
 
 func filterJoinBr(in *pongo2.Value, _ *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
@@ -0,0 +1,222 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// titleSmallWords stay lowercase in title_case unless they are the first or the last word.
+var titleSmallWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true, "by": true, "en": true, "for": true,
+	"if": true, "in": true, "nor": true, "of": true, "on": true, "or": true, "per": true, "the": true, "to": true,
+	"v": true, "vs": true, "via": true,
+}
+
+// caseLanguage parses the language of the casing rules, e.g. "tr" for the dotted and dotless i.
+func caseLanguage(lang string) language.Tag {
+	tag, err := language.Parse(strings.TrimSpace(lang))
+	if err != nil {
+		return language.Und
+	}
+
+	return tag
+}
+
+// caseParams parses the "[lang][,upper]" parameter of the case filters.
+func caseParams(param *pongo2.Value) (language.Tag, bool) {
+	tag, upper := language.Und, false
+	for _, option := range strings.Split(param.String(), ",") {
+		switch option = strings.TrimSpace(option); option {
+		case "":
+		case "upper":
+			upper = true
+		default:
+			tag = caseLanguage(option)
+		}
+	}
+
+	return tag, upper
+}
+
+// caseWords splits the text into words at everything but letters and digits and at the
+// case changes: "HTTPServer_errorCode2" is "HTTP", "Server", "error", "Code2".
+func caseWords(s string) []string {
+	var words []string
+	runes := []rune(norm.NFC.String(s))
+
+	start := -1
+	for i, r := range runes {
+		inWord := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) && start >= 0
+		if !inWord {
+			if start >= 0 {
+				words = append(words, string(runes[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+			continue
+		}
+
+		prev := runes[i-1]
+		if unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+			unicode.IsUpper(prev) && i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(runes[start:]))
+	}
+
+	return words
+}
+
+// capitalize uppercases the first letter of the word with the language rules and keeps the rest.
+func capitalize(word string, tag language.Tag) string {
+	return cases.Title(tag, cases.NoLower).String(word)
+}
+
+// filterCamelcase joins the words of the text in camelCase, "upper" makes PascalCase:
+//
+//	{{ "user id"|camelcase }}          userId
+//	{{ "user id"|camelcase:"upper" }}  UserId
+//	{{ "ılık iş"|camelcase:"tr" }}     ılıkİş
+func filterCamelcase(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	tag, upper := caseParams(param)
+	lower := cases.Lower(tag)
+
+	var b strings.Builder
+	for i, word := range caseWords(in.String()) {
+		word = lower.String(word)
+		if i > 0 || upper {
+			word = capitalize(word, tag)
+		}
+		b.WriteString(word)
+	}
+
+	return pongo2.AsValue(b.String()), nil
+}
+
+func joinLowerWords(in *pongo2.Value, param *pongo2.Value, separator string) *pongo2.Value {
+	tag, upper := caseParams(param)
+	convert := cases.Lower(tag)
+	if upper {
+		convert = cases.Upper(tag)
+	}
+
+	words := caseWords(in.String())
+	for i := range words {
+		words[i] = convert.String(words[i])
+	}
+
+	return pongo2.AsValue(strings.Join(words, separator))
+}
+
+// filterSnakeCase joins the lowercased words with "_": {{ "HTTPServer errorCode"|snake_case }} is http_server_error_code.
+// With "upper" the words are uppercased: HTTP_SERVER_ERROR_CODE.
+func filterSnakeCase(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	return joinLowerWords(in, param, "_"), nil
+}
+
+// filterKebabCase joins the lowercased words with "-": {{ "HTTPServer errorCode"|kebab_case }} is http-server-error-code.
+func filterKebabCase(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	return joinLowerWords(in, param, "-"), nil
+}
+
+// filterTitleCase capitalizes the words of the text except the small ones ("a", "of", "the"...)
+// which are not first or last. The rest of every word is kept, so acronyms stay uppercase:
+//
+//	{{ "the lord of the rings"|title_case }}  The Lord of the Rings
+//	{{ "istanbul'da bir gün"|title_case:"tr" }}
+func filterTitleCase(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	tag, _ := caseParams(param)
+	lower := cases.Lower(tag)
+
+	fields := strings.Fields(in.String())
+	for i, field := range fields {
+		if i > 0 && i < len(fields)-1 && titleSmallWords[lower.String(field)] {
+			fields[i] = lower.String(field)
+			continue
+		}
+
+		// every part of a hyphenated word is capitalized: "Well-Known"
+		parts := strings.Split(field, "-")
+		for j := range parts {
+			parts[j] = capitalize(parts[j], tag)
+		}
+		fields[i] = strings.Join(parts, "-")
+	}
+
+	return pongo2.AsValue(strings.Join(fields, " ")), nil
+}
+
+// filterSwapcase swaps the case of every letter with the language rules: {{ "Hello"|swapcase }} is hELLO.
+func filterSwapcase(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	tag, _ := caseParams(param)
+	upper, lower := cases.Upper(tag), cases.Lower(tag)
+
+	var b strings.Builder
+	for _, r := range in.String() {
+		switch {
+		case unicode.IsUpper(r) || unicode.IsTitle(r):
+			b.WriteString(lower.String(string(r)))
+		case unicode.IsLower(r):
+			b.WriteString(upper.String(string(r)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return pongo2.AsValue(b.String()), nil
+}
+
+// filterTransliterate spells the letters of the language table in Latin (see RegisterSlugTransliteration)
+// and removes the diacritical marks of Latin letters: {{ "Ärger café"|transliterate:"de" }} is "Aerger cafe".
+func filterTransliterate(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	s := transliterate(in.String(), strings.ToLower(strings.TrimSpace(param.String())))
+
+	var b strings.Builder
+	latin := false
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.IsMark(r) && latin:
+			// skip
+		default:
+			latin = unicode.Is(unicode.Latin, r)
+			b.WriteRune(r)
+		}
+	}
+
+	return pongo2.AsValue(norm.NFC.String(b.String())), nil
+}
+
+// filterNormalize returns the Unicode normalization form of the text, "NFC" by default,
+// "NFD", "NFKC" or "NFKD": {{ text|normalize:"NFKC" }}.
+func filterNormalize(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	var form norm.Form
+	switch f := strings.ToUpper(strings.TrimSpace(param.String())); f {
+	case "", "NFC":
+		form = norm.NFC
+	case "NFD":
+		form = norm.NFD
+	case "NFKC":
+		form = norm.NFKC
+	case "NFKD":
+		form = norm.NFKD
+	default:
+		return nil, &pongo2.Error{
+			Sender:    "filter:normalize",
+			OrigError: fmt.Errorf("unknown normalization form %q", f),
+		}
+	}
+
+	return pongo2.AsValue(form.String(in.String())), nil
+}
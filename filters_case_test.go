@@ -0,0 +1,61 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestCaseWords(c *C) {
+	c.Assert(caseWords("HTTPServer_errorCode2 v2Api"), DeepEquals, []string{"HTTP", "Server", "error", "Code2", "v2", "Api"})
+	c.Assert(caseWords("  user-id  "), DeepEquals, []string{"user", "id"})
+	c.Assert(caseWords("ÄrgerÜber straße"), DeepEquals, []string{"Ärger", "Über", "straße"})
+	c.Assert(caseWords("!!"), IsNil)
+}
+
+func (s *TestSuite1) TestFilterCaseConversion(c *C) {
+	ctx := pongo2.Context{"text": "HTTPServer error-code"}
+
+	c.Assert(getResult("{{ text|camelcase }}", ctx), Equals, "httpServerErrorCode")
+	c.Assert(getResult("{{ text|camelcase:'upper' }}", ctx), Equals, "HttpServerErrorCode")
+	c.Assert(getResult("{{ text|snake_case }}", ctx), Equals, "http_server_error_code")
+	c.Assert(getResult("{{ text|snake_case:'upper' }}", ctx), Equals, "HTTP_SERVER_ERROR_CODE")
+	c.Assert(getResult("{{ text|kebab_case }}", ctx), Equals, "http-server-error-code")
+
+	// Turkish dotted and dotless i
+	ctx = pongo2.Context{"text": "Iğdır ilçesi"}
+	c.Assert(getResult("{{ text|snake_case }}", ctx), Equals, "iğdır_ilçesi")
+	c.Assert(getResult("{{ text|snake_case:'tr' }}", ctx), Equals, "ığdır_ilçesi")
+	c.Assert(getResult("{{ text|snake_case:'tr,upper' }}", ctx), Equals, "IĞDIR_İLÇESİ")
+	c.Assert(getResult("{{ text|camelcase:'tr' }}", ctx), Equals, "ığdırİlçesi")
+	c.Assert(getResult("{{ text|camelcase }}", ctx), Equals, "iğdırIlçesi")
+}
+
+func (s *TestSuite1) TestFilterTitleCase(c *C) {
+	c.Assert(getResult("{{ 'the lord of the rings'|title_case }}", nil), Equals, "The Lord of the Rings")
+	c.Assert(getResult("{{ 'a tale of two cities and NASA'|title_case }}", nil), Equals, "A Tale of Two Cities and NASA")
+	c.Assert(getResult("{{ 'what we are fighting for'|title_case }}", nil), Equals, "What We Are Fighting For")
+	c.Assert(getResult("{{ 'well-known   facts'|title_case }}", nil), Equals, "Well-Known Facts")
+	c.Assert(getResult("{{ 'istanbul ve izmir'|title_case:'tr' }}", nil), Equals, "İstanbul Ve İzmir")
+	c.Assert(getResult("{{ 'istanbul'|title_case }}", nil), Equals, "Istanbul")
+}
+
+func (s *TestSuite1) TestFilterSwapcase(c *C) {
+	c.Assert(getResult("{{ 'Hello World 42'|swapcase }}", nil), Equals, "hELLO wORLD 42")
+	c.Assert(getResult("{{ 'Straße'|swapcase }}", nil), Equals, "sTRASSE")
+	c.Assert(getResult("{{ 'Iıİi'|swapcase:'tr' }}", nil), Equals, "ıIiİ")
+}
+
+func (s *TestSuite1) TestFilterTransliterateNormalize(c *C) {
+	c.Assert(getResult("{{ 'Ärger im Café'|transliterate }}", nil), Equals, "Arger im Cafe")
+	c.Assert(getResult("{{ 'Ärger im Café'|transliterate:'de' }}", nil), Equals, "Aerger im Cafe")
+	c.Assert(getResult("{{ 'Щука и ёж'|transliterate:'ru' }}", nil), Equals, "Shchuka i yozh")
+	c.Assert(getResult("{{ 'Ёж'|transliterate }}", nil), Equals, "Ёж")
+
+	ctx := pongo2.Context{"text": "e\u0301 \ufb01"}
+	c.Assert(getResult("{{ text|normalize }}", ctx), Equals, "\u00e9 \ufb01")
+	c.Assert(getResult("{{ text|normalize:'nfkc' }}", ctx), Equals, "\u00e9 fi")
+	c.Assert(getResult("{{ text|normalize:'NFD' }}", ctx), Equals, "e\u0301 \ufb01")
+	c.Assert(getResult("{{ text|normalize:'NFKD' }}", ctx), Equals, "e\u0301 fi")
+	c.Assert(getResult("{{ text|normalize:'XYZ' }}", ctx), Equals, "")
+}
@@ -0,0 +1,553 @@
+package pongo2addons
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+func collectionError(sender string, msg string) *pongo2.Error {
+	return &pongo2.Error{
+		Sender:    "filter:" + sender,
+		OrigError: errors.New(msg),
+	}
+}
+
+// collectionItems returns the elements of a slice or an array value (or a pointer to one).
+func collectionItems(in *pongo2.Value, sender string) ([]interface{}, *pongo2.Error) {
+	v := queryIndirect(reflect.ValueOf(in.Interface()))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, collectionError(sender, "input is not a slice")
+	}
+
+	items := make([]interface{}, v.Len())
+	for i := range items {
+		items[i] = v.Index(i).Interface()
+	}
+
+	return items, nil
+}
+
+// collectionKey returns the field of the item selected by a dotted path, or the item itself
+// if the path is empty. Pointers are dereferenced.
+func collectionKey(item interface{}, field string) (interface{}, bool) {
+	if field != "" {
+		var ok bool
+		if item, ok = queryFieldPath(item, field); !ok {
+			return nil, false
+		}
+	}
+
+	v := queryIndirect(reflect.ValueOf(item))
+	if !v.IsValid() {
+		return nil, true
+	}
+
+	return v.Interface(), true
+}
+
+// collectionCompare orders numbers, strings, booleans and times; other values are not ordered.
+func collectionCompare(a, b interface{}) (int, bool) {
+	if af, ok := queryNumber(a); ok {
+		bf, ok := queryNumber(b)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		}
+		return 0, true
+	}
+
+	if at, ok := a.(time.Time); ok {
+		bt, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case at.Before(bt):
+			return -1, true
+		case at.After(bt):
+			return 1, true
+		}
+		return 0, true
+	}
+
+	// named types like "type Status string" are ordered by their kind
+	av, bv := queryIndirect(reflect.ValueOf(a)), queryIndirect(reflect.ValueOf(b))
+	if !av.IsValid() || !bv.IsValid() || av.Kind() != bv.Kind() {
+		return 0, false
+	}
+	switch av.Kind() {
+	case reflect.String:
+		return strings.Compare(av.String(), bv.String()), true
+	case reflect.Bool:
+		switch {
+		case av.Bool() == bv.Bool():
+			return 0, true
+		case bv.Bool():
+			return -1, true
+		}
+		return 1, true
+	}
+
+	return 0, false
+}
+
+// collectionSet finds equal keys, hashing the basic values and comparing the others deeply.
+type collectionSet struct {
+	hashed map[interface{}]int
+	keys   []interface{}
+	index  []int
+}
+
+func newCollectionSet() *collectionSet {
+	return &collectionSet{hashed: map[interface{}]int{}}
+}
+
+func isCollectionHashable(key interface{}) bool {
+	switch reflect.ValueOf(key).Kind() {
+	case reflect.Invalid, reflect.Bool, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32,
+		reflect.Float64:
+		return true
+	}
+
+	return false
+}
+
+// find returns the index stored with the key.
+func (s *collectionSet) find(key interface{}) (int, bool) {
+	if isCollectionHashable(key) {
+		idx, ok := s.hashed[key]
+		return idx, ok
+	}
+	for i, k := range s.keys {
+		if reflect.DeepEqual(k, key) {
+			return s.index[i], true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *collectionSet) add(key interface{}, idx int) {
+	if isCollectionHashable(key) {
+		s.hashed[key] = idx
+		return
+	}
+	s.keys = append(s.keys, key)
+	s.index = append(s.index, idx)
+}
+
+// filterSortBy sorts the items by their fields, a leading "-" sorts in descending order:
+//
+//	{{ users|sort_by:"LastName,-Age" }}
+//
+// Items without the field go last, the order of equal items is kept.
+func filterSortBy(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "sort_by")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []string
+	for _, f := range strings.Split(param.String(), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			fields = append(fields, f)
+		}
+	}
+	if len(fields) == 0 {
+		return nil, collectionError("sort_by", "field name is empty")
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		for _, field := range fields {
+			desc := strings.HasPrefix(field, "-")
+			field = strings.TrimPrefix(field, "-")
+
+			a, okA := collectionKey(items[i], field)
+			b, okB := collectionKey(items[j], field)
+			switch {
+			case !okA && !okB:
+				continue
+			case !okA:
+				return false
+			case !okB:
+				return true
+			}
+
+			c, ok := collectionCompare(a, b)
+			if !ok {
+				c = strings.Compare(pongo2.AsValue(a).String(), pongo2.AsValue(b).String())
+			}
+			if c != 0 {
+				return c < 0 != desc
+			}
+		}
+		return false
+	})
+
+	return pongo2.AsValue(items), nil
+}
+
+// filterGroupBy groups the items by the field in the order the groups first appear:
+//
+//	{% for group in posts|group_by:"Category" %}{{ group.grouper }}: {{ group.list|length }}{% endfor %}
+func filterGroupBy(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "group_by")
+	if err != nil {
+		return nil, err
+	}
+
+	field := strings.TrimSpace(param.String())
+	if field == "" {
+		return nil, collectionError("group_by", "field name is empty")
+	}
+
+	var groups []map[string]interface{}
+	set := newCollectionSet()
+	for _, item := range items {
+		key, _ := collectionKey(item, field)
+		idx, ok := set.find(key)
+		if !ok {
+			idx = len(groups)
+			set.add(key, idx)
+			groups = append(groups, map[string]interface{}{"grouper": key, "list": []interface{}{}})
+		}
+		groups[idx]["list"] = append(groups[idx]["list"].([]interface{}), item)
+	}
+
+	return pongo2.AsValue(groups), nil
+}
+
+// filterUnique removes the repeated items, or the items with a repeated field: {{ tags|unique }}, {{ users|unique:"Email" }}.
+func filterUnique(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "unique")
+	if err != nil {
+		return nil, err
+	}
+
+	field := strings.TrimSpace(param.String())
+	out := make([]interface{}, 0, len(items))
+	set := newCollectionSet()
+	for _, item := range items {
+		key, _ := collectionKey(item, field)
+		if _, ok := set.find(key); ok {
+			continue
+		}
+		set.add(key, len(out))
+		out = append(out, item)
+	}
+
+	return pongo2.AsValue(out), nil
+}
+
+// filterChunk splits the items into lists of the given size, the last one may be shorter: {{ items|chunk:"3" }}.
+func filterChunk(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "chunk")
+	if err != nil {
+		return nil, err
+	}
+
+	size := param.Integer()
+	if size < 1 {
+		return nil, collectionError("chunk", "chunk size is less than 1")
+	}
+
+	chunks := make([][]interface{}, 0, (len(items)+size-1)/size)
+	for i := 0; i < len(items); i += size {
+		chunks = append(chunks, items[i:min(i+size, len(items))])
+	}
+
+	return pongo2.AsValue(chunks), nil
+}
+
+// filterZip pairs the items with the items of the parameter list, up to the shorter one:
+//
+//	{% for pair in names|zip:scores %}{{ pair.0 }}: {{ pair.1 }}{% endfor %}
+func filterZip(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "zip")
+	if err != nil {
+		return nil, err
+	}
+	others, err := collectionItems(param, "zip")
+	if err != nil {
+		return nil, collectionError("zip", "parameter is not a slice")
+	}
+
+	pairs := make([][]interface{}, min(len(items), len(others)))
+	for i := range pairs {
+		pairs[i] = []interface{}{items[i], others[i]}
+	}
+
+	return pongo2.AsValue(pairs), nil
+}
+
+// filterFlatten merges the nested lists into a single one: {{ lists|flatten }}.
+// The parameter limits the depth, all levels are merged by default.
+func filterFlatten(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "flatten")
+	if err != nil {
+		return nil, err
+	}
+
+	depth := param.Integer()
+	if depth <= 0 {
+		depth = -1
+	}
+
+	return pongo2.AsValue(flattenItems(items, depth, nil)), nil
+}
+
+func flattenItems(items []interface{}, depth int, out []interface{}) []interface{} {
+	for _, item := range items {
+		v := queryIndirect(reflect.ValueOf(item))
+		if depth == 0 || v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			out = append(out, item)
+			continue
+		}
+
+		nested := make([]interface{}, v.Len())
+		for i := range nested {
+			nested[i] = v.Index(i).Interface()
+		}
+		out = flattenItems(nested, depth-1, out)
+	}
+
+	return out
+}
+
+// filterPluck returns the field of every item, items without it are skipped: {{ users|pluck:"Name" }}.
+func filterPluck(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "pluck")
+	if err != nil {
+		return nil, err
+	}
+
+	field := strings.TrimSpace(param.String())
+	if field == "" {
+		return nil, collectionError("pluck", "field name is empty")
+	}
+
+	out := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		if value, ok := queryFieldPath(item, field); ok {
+			out = append(out, value)
+		}
+	}
+
+	return pongo2.AsValue(out), nil
+}
+
+// filterWhere keeps the items with the field equal to the value, or with a true field if the value is omitted:
+//
+//	{{ users|where:"Role,admin" }}
+//	{{ users|where:"Active" }}
+func filterWhere(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, "where")
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.SplitN(param.String(), ",", 2)
+	field := strings.TrimSpace(parts[0])
+	if field == "" {
+		return nil, collectionError("where", "field name is empty")
+	}
+
+	out := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		value, ok := collectionKey(item, field)
+		if !ok {
+			continue
+		}
+
+		if len(parts) == 1 {
+			ok = pongo2.AsValue(value).IsTrue()
+		} else if b := queryIndirect(reflect.ValueOf(value)); b.Kind() == reflect.Bool {
+			operand, err := strconv.ParseBool(strings.TrimSpace(parts[1]))
+			ok = err == nil && operand == b.Bool()
+		} else if n, isNumber := queryNumber(value); isNumber {
+			f, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			ok = err == nil && f == n
+		} else {
+			ok = pongo2.AsValue(value).String() == strings.TrimSpace(parts[1])
+		}
+		if ok {
+			out = append(out, item)
+		}
+	}
+
+	return pongo2.AsValue(out), nil
+}
+
+// collectionNumbers returns the numbers of the items or their fields.
+func collectionNumbers(in *pongo2.Value, param *pongo2.Value, sender string) ([]interface{}, *pongo2.Error) {
+	items, err := collectionItems(in, sender)
+	if err != nil {
+		return nil, err
+	}
+
+	field := strings.TrimSpace(param.String())
+	numbers := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		value, ok := collectionKey(item, field)
+		if !ok {
+			continue
+		}
+		if _, ok := queryNumber(value); !ok {
+			return nil, collectionError(sender, "item is not a number")
+		}
+		numbers = append(numbers, value)
+	}
+
+	return numbers, nil
+}
+
+// collectionSum adds up the numbers. Integers are added exactly as int64, or as uint64 if the
+// sum doesn't fit, floats or an overflow make the sum a float64.
+func collectionSum(numbers []interface{}) interface{} {
+	var signed int64
+	var unsigned uint64
+	signedOK, unsignedOK := true, true
+	sum := 0.0
+
+	addSigned := func(n int64) {
+		s := signed + n
+		if n > 0 && s < signed || n < 0 && s > signed {
+			signedOK = false
+		}
+		signed = s
+	}
+	addUnsigned := func(n uint64) {
+		s := unsigned + n
+		if s < unsigned {
+			unsignedOK = false
+		}
+		unsigned = s
+	}
+
+	for _, n := range numbers {
+		f, _ := queryNumber(n)
+		sum += f
+
+		v := reflect.ValueOf(n)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if signedOK {
+				addSigned(v.Int())
+			}
+			if v.Int() < 0 {
+				unsignedOK = false
+			} else if unsignedOK {
+				addUnsigned(uint64(v.Int()))
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+			if v.Uint() > math.MaxInt64 {
+				signedOK = false
+			} else if signedOK {
+				addSigned(int64(v.Uint()))
+			}
+			if unsignedOK {
+				addUnsigned(v.Uint())
+			}
+		default:
+			signedOK, unsignedOK = false, false
+		}
+	}
+
+	switch {
+	case signedOK:
+		return signed
+	case unsignedOK:
+		return unsigned
+	}
+
+	return sum
+}
+
+// filterSum adds up the items or their field: {{ prices|sum }}, {{ orders|sum:"Total" }}.
+// The sum of integers is an exact integer.
+func filterSum(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	numbers, err := collectionNumbers(in, param, "sum")
+	if err != nil {
+		return nil, err
+	}
+
+	return pongo2.AsValue(collectionSum(numbers)), nil
+}
+
+// filterAvg returns the arithmetic mean of the items or their field: {{ scores|avg }}, {{ users|avg:"Age" }}.
+func filterAvg(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	numbers, err := collectionNumbers(in, param, "avg")
+	if err != nil {
+		return nil, err
+	}
+	if len(numbers) == 0 {
+		return nil, collectionError("avg", "input slice is empty")
+	}
+
+	sum := 0.0
+	for _, n := range numbers {
+		f, _ := queryNumber(n)
+		sum += f
+	}
+
+	return pongo2.AsValue(sum / float64(len(numbers))), nil
+}
+
+// collectionExtreme returns the smallest (sign -1) or the largest (sign 1) item. With a field
+// the items are compared by it and the whole item is returned.
+func collectionExtreme(in *pongo2.Value, param *pongo2.Value, sender string, sign int) (*pongo2.Value, *pongo2.Error) {
+	items, err := collectionItems(in, sender)
+	if err != nil {
+		return nil, err
+	}
+
+	field := strings.TrimSpace(param.String())
+	var best, bestKey interface{}
+	found := false
+	for _, item := range items {
+		key, ok := collectionKey(item, field)
+		if !ok {
+			continue
+		}
+		if !found {
+			best, bestKey, found = item, key, true
+			continue
+		}
+
+		c, ok := collectionCompare(key, bestKey)
+		if !ok {
+			return nil, collectionError(sender, "items are not comparable")
+		}
+		if c*sign > 0 {
+			best, bestKey = item, key
+		}
+	}
+	if !found {
+		return nil, collectionError(sender, "input slice is empty")
+	}
+
+	return pongo2.AsValue(best), nil
+}
+
+// filterMin returns the smallest item: {{ prices|min }}, {{ products|min:"Price" }}.
+func filterMin(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	return collectionExtreme(in, param, "min", -1)
+}
+
+// filterMax returns the largest item: {{ prices|max }}, {{ products|max:"Price" }}.
+func filterMax(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	return collectionExtreme(in, param, "max", 1)
+}
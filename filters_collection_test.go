@@ -0,0 +1,155 @@
+package pongo2addons
+
+import (
+	"math"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+type collectionProduct struct {
+	Name     string  `json:"name"`
+	Category string  `json:"category"`
+	Price    float64 `json:"price"`
+	Stock    int
+	Active   bool
+}
+
+type collectionStatus string
+
+type collectionTask struct {
+	Name   string
+	Status collectionStatus
+	Done   collectionFlag
+}
+
+type collectionFlag bool
+
+func collectionContext() pongo2.Context {
+	return pongo2.Context{
+		"products": []*collectionProduct{
+			{Name: "pen", Category: "office", Price: 1.5, Stock: 10, Active: true},
+			{Name: "desk", Category: "furniture", Price: 120, Stock: 2},
+			{Name: "paper", Category: "office", Price: 4, Stock: 0, Active: true},
+			{Name: "chair", Category: "furniture", Price: 45.5, Stock: 2, Active: true},
+		},
+		"maps": []map[string]interface{}{
+			{"id": 3, "tag": "b"}, {"id": 1, "tag": "a"}, {"tag": "c"}, {"id": 2, "tag": "a"},
+		},
+		"numbers": []int{3, 1, 2, 3, 1},
+		"nested":  []interface{}{1, []int{2, 3}, []interface{}{4, []int{5}}},
+		"empty":   []int{},
+	}
+}
+
+func (s *TestSuite1) TestFilterSortBy(c *C) {
+	ctx := collectionContext()
+
+	c.Assert(getResult("{{ products|sort_by:'Price'|join_attr:'Name' }}", ctx), Equals, "pen, paper, chair, desk")
+	c.Assert(getResult("{{ products|sort_by:'-price'|join_attr:'Name' }}", ctx), Equals, "desk, chair, paper, pen")
+	c.Assert(getResult("{{ products|sort_by:'Stock,name'|join_attr:'Name' }}", ctx), Equals, "paper, chair, desk, pen")
+	c.Assert(getResult("{{ products|sort_by:'-Stock,-name'|join_attr:'Name' }}", ctx), Equals, "pen, desk, chair, paper")
+
+	// items without the field go last
+	c.Assert(getResult("{{ maps|sort_by:'id'|join_attr:'tag,' }}", ctx), Equals, "aabc")
+	c.Assert(getResult("{{ maps|sort_by:'-id'|join_attr:'tag,' }}", ctx), Equals, "baac")
+
+	// named types are ordered by their kind
+	ctx["tasks"] = []collectionTask{{"write", "todo", false}, {"plan", "done", true}, {"test", "doing", false}}
+	c.Assert(getResult("{{ tasks|sort_by:'Status'|join_attr:'Name' }}", ctx), Equals, "test, plan, write")
+	c.Assert(getResult("{{ tasks|sort_by:'-Done,Name'|join_attr:'Name' }}", ctx), Equals, "plan, test, write")
+	c.Assert(getResult("{% with t=tasks|min:'Status' %}{{ t.Name }}{% endwith %}", ctx), Equals, "test")
+	c.Assert(getResult("{% with t=tasks|max:'Status' %}{{ t.Name }}{% endwith %}", ctx), Equals, "write")
+	c.Assert(getResult("{{ tasks|where:'Done,true'|join_attr:'Name' }}", ctx), Equals, "plan")
+
+	// errors
+	c.Assert(getResult("{{ products|sort_by:'' }}", ctx), Equals, "")
+	c.Assert(getResult("{{ 'abc'|sort_by:'Name' }}", ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterGroupBy(c *C) {
+	ctx := collectionContext()
+
+	c.Assert(getResult("{% for g in products|group_by:'Category' %}{{ g.grouper }}: {{ g.list|join_attr:'Name' }}; {% endfor %}", ctx),
+		Equals, "office: pen, paper; furniture: desk, chair; ")
+	c.Assert(getResult("{% for g in maps|group_by:'tag' %}{{ g.grouper }}{{ g.list|length }} {% endfor %}", ctx),
+		Equals, "b1 a2 c1 ")
+
+	c.Assert(getResult("{{ products|group_by:'' }}", ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterUniqueChunkZipFlatten(c *C) {
+	ctx := collectionContext()
+
+	c.Assert(getResult("{{ numbers|unique|join:',' }}", ctx), Equals, "3,1,2")
+	c.Assert(getResult("{{ products|unique:'Stock'|join_attr:'Name' }}", ctx), Equals, "pen, desk, paper")
+	c.Assert(getResult("{{ nested|unique|length }}", ctx), Equals, "3")
+
+	c.Assert(getResult("{% for ch in numbers|chunk:'2' %}[{{ ch|join:',' }}]{% endfor %}", ctx), Equals, "[3,1][2,3][1]")
+	c.Assert(getResult("{% for ch in numbers|chunk:5 %}[{{ ch|join:',' }}]{% endfor %}", ctx), Equals, "[3,1,2,3,1]")
+	c.Assert(getResult("{{ numbers|chunk:'0' }}", ctx), Equals, "")
+
+	c.Assert(getResult("{% for p in numbers|zip:products %}{{ p.0 }}={{ p.1.Name }} {% endfor %}", ctx), Equals,
+		"3=pen 1=desk 2=paper 3=chair ")
+	c.Assert(getResult("{{ numbers|zip:'abc' }}", ctx), Equals, "")
+
+	c.Assert(getResult("{{ nested|flatten|join:',' }}", ctx), Equals, "1,2,3,4,5")
+	c.Assert(getResult("{{ nested|flatten:1|length }}", ctx), Equals, "5")
+	c.Assert(getResult("{{ 5|flatten }}", ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterPluckWhere(c *C) {
+	ctx := collectionContext()
+
+	c.Assert(getResult("{{ products|pluck:'name'|join:',' }}", ctx), Equals, "pen,desk,paper,chair")
+	c.Assert(getResult("{{ maps|pluck:'id'|join:',' }}", ctx), Equals, "3,1,2")
+	c.Assert(getResult("{{ products|pluck:'' }}", ctx), Equals, "")
+
+	c.Assert(getResult("{{ products|where:'Category,office'|join_attr:'Name' }}", ctx), Equals, "pen, paper")
+	c.Assert(getResult("{{ products|where:'Stock,2'|join_attr:'Name' }}", ctx), Equals, "desk, chair")
+	c.Assert(getResult("{{ products|where:'price,4.0'|join_attr:'Name' }}", ctx), Equals, "paper")
+	c.Assert(getResult("{{ products|where:'Active'|join_attr:'Name' }}", ctx), Equals, "pen, paper, chair")
+	c.Assert(getResult("{{ products|where:'Active,true'|join_attr:'Name' }}", ctx), Equals, "pen, paper, chair")
+	c.Assert(getResult("{{ products|where:'Active, false'|join_attr:'Name' }}", ctx), Equals, "desk")
+	c.Assert(getResult("{{ products|where:'Active,True'|length }}", ctx), Equals, "3")
+	c.Assert(getResult("{{ products|where:'Active,yes'|length }}", ctx), Equals, "0")
+	c.Assert(getResult("{{ products|where:'Category, office'|join_attr:'Name' }}", ctx), Equals, "pen, paper")
+	c.Assert(getResult("{{ products|where:'Stock, 2'|join_attr:'Name' }}", ctx), Equals, "desk, chair")
+	c.Assert(getResult("{{ products|where:'Stock'|join_attr:'Name' }}", ctx), Equals, "pen, desk, chair")
+	c.Assert(getResult("{{ products|where:'' }}", ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterAggregates(c *C) {
+	ctx := collectionContext()
+
+	c.Assert(getResult("{{ numbers|sum }}", ctx), Equals, "10")
+	c.Assert(getResult("{{ products|sum:'Stock' }}", ctx), Equals, "14")
+	c.Assert(getResult("{{ products|sum:'Price' }}", ctx), Equals, "171.000000")
+	c.Assert(getResult("{{ empty|sum }}", ctx), Equals, "0")
+	c.Assert(getResult("{{ maps|sum:'id' }}", ctx), Equals, "6")
+	c.Assert(getResult("{{ products|sum:'Name' }}", ctx), Equals, "")
+
+	// integers are added exactly
+	ctx["big"] = []int64{9007199254740992, 1}
+	c.Assert(getResult("{{ big|sum }}", ctx), Equals, "9007199254740993")
+	ctx["big"] = []interface{}{uint64(math.MaxUint64 - 1), 1}
+	c.Assert(getResult("{{ big|sum }}", ctx), Equals, "18446744073709551615")
+	ctx["big"] = []interface{}{int64(math.MaxInt64), -2, 1}
+	c.Assert(getResult("{{ big|sum }}", ctx), Equals, "9223372036854775806")
+	ctx["big"] = []interface{}{9007199254740992, 1, 0.5}
+	c.Assert(getResult("{{ big|sum }}", ctx), Equals, "9007199254740992.000000")
+
+	c.Assert(getResult("{{ numbers|avg }}", ctx), Equals, "2.000000")
+	c.Assert(getResult("{{ products|avg:'Price'|floatformat:2 }}", ctx), Equals, "42.75")
+	c.Assert(getResult("{{ empty|avg }}", ctx), Equals, "")
+
+	c.Assert(getResult("{{ numbers|min }}", ctx), Equals, "1")
+	c.Assert(getResult("{{ numbers|max }}", ctx), Equals, "3")
+	c.Assert(getResult("{% with p=products|min:'Price' %}{{ p.Name }}{% endwith %}", ctx), Equals, "pen")
+	c.Assert(getResult("{% with p=products|max:'Price' %}{{ p.Name }}{% endwith %}", ctx), Equals, "desk")
+	c.Assert(getResult("{% with p=products|max:'Name' %}{{ p.Name }}{% endwith %}", ctx), Equals, "pen")
+	c.Assert(getResult("{{ empty|min }}", ctx), Equals, "")
+	c.Assert(getResult("{{ nested|max }}", ctx), Equals, "")
+	c.Assert(getResult("{{ 'abc'|max }}", ctx), Equals, "")
+}
@@ -0,0 +1,130 @@
+package pongo2addons
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// ErrorFields is implemented by errors which expose fields, like a status code, to the error_field filter.
+type ErrorFields interface {
+	ErrorFields() map[string]interface{}
+}
+
+var (
+	errorRegistryMu sync.RWMutex
+
+	// errorRegistry keeps the sentinel errors for error_is by name.
+	errorRegistry = map[string]error{}
+)
+
+// RegisterError adds a sentinel error for the error_is filter, the name is what templates use:
+//
+//	pongo2addons.RegisterError("storage.ErrNotFound", storage.ErrNotFound)
+func RegisterError(name string, err error) {
+	errorRegistryMu.Lock()
+	defer errorRegistryMu.Unlock()
+
+	errorRegistry[name] = err
+}
+
+func registeredError(name string) (error, bool) {
+	errorRegistryMu.RLock()
+	defer errorRegistryMu.RUnlock()
+
+	err, ok := errorRegistry[name]
+	return err, ok
+}
+
+// errorChain returns the error and all errors it wraps, depth-first. Errors joining
+// several ones with Unwrap() []error are followed as well. A typed nil pointer ends the chain
+// like nil, its methods are not called.
+func errorChain(err error, out []error) []error {
+	for !isNilError(err) {
+		out = append(out, err)
+
+		if multi, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range multi.Unwrap() {
+				out = errorChain(e, out)
+			}
+			return out
+		}
+		err = errors.Unwrap(err)
+	}
+
+	return out
+}
+
+// filterErrorChain returns the error and the errors it wraps as a slice, it is empty for nil and values which are not errors:
+//
+//	{% for e in err|error_chain %}<li>{{ e|printerror }}</li>{% endfor %}
+func filterErrorChain(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	err, _ := in.Interface().(error)
+
+	return pongo2.AsValue(errorChain(err, []error{})), nil
+}
+
+// filterErrorIs reports whether the error matches the sentinel error registered with RegisterError, see errors.Is:
+//
+//	{% if err|error_is:"storage.ErrNotFound" %}Not found{% endif %}
+func filterErrorIs(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	name := strings.TrimSpace(param.String())
+	target, ok := registeredError(name)
+	if !ok {
+		return nil, &pongo2.Error{
+			Sender:    "filter:error_is",
+			OrigError: fmt.Errorf("error %q is not registered", name),
+		}
+	}
+
+	err, _ := in.Interface().(error)
+
+	return pongo2.AsValue(errorIs(err, target)), nil
+}
+
+// errorIs works like errors.Is on the chain of errorChain, so typed nil pointers are skipped.
+func errorIs(err error, target error) bool {
+	for _, e := range errorChain(err, nil) {
+		if reflect.TypeOf(e).Comparable() && e == target {
+			return true
+		}
+		if is, ok := e.(interface{ Is(error) bool }); ok && is.Is(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// filterErrorType returns the dynamic type name of the error, like "*fs.PathError", or an empty string for nil.
+// Typed nil pointers report their type.
+func filterErrorType(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if in.Interface() == nil {
+		return pongo2.AsValue(""), nil
+	}
+
+	return pongo2.AsValue(fmt.Sprintf("%T", in.Interface())), nil
+}
+
+// filterErrorField returns the field of the first error in the chain which exposes it
+// through the ErrorFields interface, or nil:
+//
+//	{% if err|error_field:"status" == 404 %}Not found{% endif %}
+func filterErrorField(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	err, _ := in.Interface().(error)
+	name := strings.TrimSpace(param.String())
+
+	for _, e := range errorChain(err, nil) {
+		if fields, ok := e.(ErrorFields); ok {
+			if value, ok := fields.ErrorFields()[name]; ok {
+				return pongo2.AsValue(value), nil
+			}
+		}
+	}
+
+	return pongo2.AsValue(nil), nil
+}
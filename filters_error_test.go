@@ -0,0 +1,77 @@
+package pongo2addons
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+var errTestNotFound = errors.New("not found")
+
+type testStatusError struct {
+	status int
+	err    error
+}
+
+func (e *testStatusError) Error() string { return fmt.Sprintf("status %d: %v", e.status, e.err) }
+
+func (e *testStatusError) Unwrap() error { return e.err }
+
+func (e *testStatusError) ErrorFields() map[string]interface{} {
+	return map[string]interface{}{"status": e.status}
+}
+
+type testJoinedError []error
+
+func (e testJoinedError) Error() string { return "joined" }
+
+func (e testJoinedError) Unwrap() []error { return e }
+
+func (s *TestSuite1) TestFilterErrorIntrospection(c *C) {
+	RegisterError("test.ErrNotFound", errTestNotFound)
+	RegisterError("fs.ErrNotExist", fs.ErrNotExist)
+
+	wrapped := fmt.Errorf("loading page: %w", &testStatusError{status: 404, err: errTestNotFound})
+	ctx := pongo2.Context{
+		"err":    wrapped,
+		"plain":  errors.New("plain"),
+		"joined": testJoinedError{errors.New("first"), fmt.Errorf("second: %w", fs.ErrNotExist)},
+		"none":   nil,
+		"text":   "not an error",
+	}
+
+	c.Assert(getResult("{% for e in err|error_chain %}[{{ e|printerror }}]{% endfor %}", ctx), Equals,
+		"[loading page: status 404: not found][status 404: not found][not found]")
+	c.Assert(getResult("{% for e in joined|error_chain %}[{{ e|printerror }}]{% endfor %}", ctx), Equals,
+		"[joined][first][second: file does not exist][file does not exist]")
+	c.Assert(getResult("{{ none|error_chain|length }}{{ text|error_chain|length }}", ctx), Equals, "00")
+
+	c.Assert(getResult("{% if err|error_is:'test.ErrNotFound' %}yes{% else %}no{% endif %}", ctx), Equals, "yes")
+	c.Assert(getResult("{% if plain|error_is:'test.ErrNotFound' %}yes{% else %}no{% endif %}", ctx), Equals, "no")
+	c.Assert(getResult("{% if joined|error_is:'fs.ErrNotExist' %}yes{% else %}no{% endif %}", ctx), Equals, "yes")
+	c.Assert(getResult("{% if none|error_is:'fs.ErrNotExist' %}yes{% else %}no{% endif %}", ctx), Equals, "no")
+	c.Assert(getResult("{{ err|error_is:'unknown' }}", ctx), Equals, "")
+
+	c.Assert(getResult("{{ err|error_type }}", ctx), Equals, "*fmt.wrapError")
+	c.Assert(getResult("{{ joined|error_type }}", ctx), Equals, "pongo2addons.testJoinedError")
+	c.Assert(getResult("{{ none|error_type }}", ctx), Equals, "")
+
+	c.Assert(getResult("{{ err|error_field:'status' }}", ctx), Equals, "404")
+	c.Assert(getResult("{% if err|error_field:'status' == 404 %}Not found{% endif %}", ctx), Equals, "Not found")
+	c.Assert(getResult("{{ err|error_field:'missing' }}{{ plain|error_field:'status' }}", ctx), Equals, "")
+
+	// typed nil pointers are no errors, but keep their type
+	var typedNil *testStatusError
+	ctx["typednil"] = typedNil
+	ctx["wrappednil"] = fmt.Errorf("wrapped: %w", typedNil)
+	c.Assert(getResult("{{ typednil|error_chain|length }}", ctx), Equals, "0")
+	c.Assert(getResult("{% for e in wrappednil|error_chain %}[{{ e|printerror }}]{% endfor %}", ctx), Equals, "[wrapped: &lt;nil&gt;]")
+	c.Assert(getResult("{% if typednil|error_is:'test.ErrNotFound' %}yes{% else %}no{% endif %}", ctx), Equals, "no")
+	c.Assert(getResult("{% if wrappednil|error_is:'test.ErrNotFound' %}yes{% else %}no{% endif %}", ctx), Equals, "no")
+	c.Assert(getResult("{{ typednil|error_field:'status' }}{{ wrappednil|error_field:'status' }}", ctx), Equals, "")
+	c.Assert(getResult("{{ typednil|error_type }}", ctx), Equals, "*pongo2addons.testStatusError")
+}
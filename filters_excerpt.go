@@ -0,0 +1,195 @@
+package pongo2addons
+
+import (
+	"html"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+const (
+	// excerptDefaultWords is the size of the excerpt window in words.
+	excerptDefaultWords = 30
+	excerptEllipsis     = "…"
+
+	// readingTimeDefaultWPM is the reading speed in words per minute.
+	readingTimeDefaultWPM = 200
+)
+
+// textWord is a word of the text given by its rune offsets.
+type textWord struct {
+	start, end int
+}
+
+// htmlWords returns the visible text of the value and its words. The text may be plain
+// or HTML, tags are skipped and the boundaries of block elements separate words.
+func htmlWords(value string) ([]rune, []textWord) {
+	text, _ := htmlSentenceText(value)
+
+	var words []textWord
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			words = append(words, textWord{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, textWord{start: start, end: len(text)})
+	}
+
+	return text, words
+}
+
+// filterExcerpt returns the part of the text around the query terms, the terms are highlighted with <mark>:
+//
+//	{{ article.body|excerpt:"query terms,20" }}
+//
+// A term matches the words starting with it, case-insensitively. Without matches the beginning of the text is returned.
+func filterExcerpt(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	terms := strings.Fields(strings.ToLower(pongoParam(param, 0).String()))
+	size := excerptDefaultWords
+	if strings.TrimSpace(pongoParam(param, 1).String()) != "" {
+		size = max(pongoParam(param, 1).Integer(), 1)
+	}
+
+	text, words := htmlWords(in.String())
+	if len(words) == 0 {
+		return pongo2.AsSafeValue(""), nil
+	}
+
+	// index of the first matched term per word, -1 if none
+	matches := make([]int, len(words))
+	for i, w := range words {
+		matches[i] = -1
+		word := strings.ToLower(string(text[w.start:w.end]))
+		for t, term := range terms {
+			if strings.HasPrefix(word, term) {
+				matches[i] = t
+				break
+			}
+		}
+	}
+
+	from := excerptBestWindow(matches, len(terms), size)
+	to := min(from+size, len(words))
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString(excerptEllipsis)
+	} else {
+		b.WriteString(html.EscapeString(strings.TrimLeftFunc(collapseSpaces(text[:words[0].start]), unicode.IsSpace)))
+	}
+	for i := from; i < to; i++ {
+		if i > from {
+			b.WriteString(html.EscapeString(collapseSpaces(text[words[i-1].end:words[i].start])))
+		}
+
+		word := html.EscapeString(string(text[words[i].start:words[i].end]))
+		if matches[i] >= 0 {
+			word = "<mark>" + word + "</mark>"
+		}
+		b.WriteString(word)
+	}
+	if to < len(words) {
+		b.WriteString(excerptEllipsis)
+	} else {
+		b.WriteString(html.EscapeString(strings.TrimRightFunc(collapseSpaces(text[words[to-1].end:]), unicode.IsSpace)))
+	}
+
+	return pongo2.AsSafeValue(b.String()), nil
+}
+
+// collapseSpaces replaces every run of whitespace with a single space.
+func collapseSpaces(text []rune) string {
+	var b strings.Builder
+	space := false
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			b.WriteRune(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+	if space {
+		b.WriteRune(' ')
+	}
+
+	return b.String()
+}
+
+// excerptBestWindow returns the first word of the window which covers most distinct terms,
+// then most matches in total.
+func excerptBestWindow(matches []int, terms int, size int) int {
+	if terms == 0 || len(matches) <= size {
+		return 0
+	}
+
+	// counts of the terms in the window, which slides one word at a time
+	counts := make(map[int]int, terms)
+	total := 0
+	add := func(m int, delta int) {
+		if m < 0 {
+			return
+		}
+		counts[m] += delta
+		if counts[m] == 0 {
+			delete(counts, m)
+		}
+		total += delta
+	}
+	for _, m := range matches[:size] {
+		add(m, 1)
+	}
+
+	best, bestDistinct, bestTotal := 0, len(counts), total
+	for from := 1; from+size <= len(matches); from++ {
+		add(matches[from-1], -1)
+		add(matches[from+size-1], 1)
+		if len(counts) > bestDistinct || len(counts) == bestDistinct && total > bestTotal {
+			best, bestDistinct, bestTotal = from, len(counts), total
+		}
+	}
+
+	// move the window to give the first match some context before it and more text after it
+	if bestTotal > 0 {
+		first := best
+		for matches[first] < 0 {
+			first++
+		}
+		best = max(best, first-size/4)
+	}
+
+	return min(best, len(matches)-size)
+}
+
+// filterReadingTime estimates the reading time of the text (plain or HTML) in whole minutes:
+//
+//	{{ article.body|readingtime }} min read
+//
+// The parameter is the reading speed in words per minute, 200 by default.
+func filterReadingTime(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	wpm := readingTimeDefaultWPM
+	if param.Integer() > 0 {
+		wpm = param.Integer()
+	}
+
+	_, words := htmlWords(in.String())
+	if len(words) == 0 {
+		return pongo2.AsValue(0), nil
+	}
+
+	return pongo2.AsValue(int(math.Ceil(float64(len(words)) / float64(wpm)))), nil
+}
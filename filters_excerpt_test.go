@@ -0,0 +1,55 @@
+package pongo2addons
+
+import (
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterExcerpt(c *C) {
+	text := "Pongo2 is a template engine. It is written in Go and is inspired by Django. " +
+		"Templates are compiled once and executed many times. Filters transform values, " +
+		"for example the excerpt filter finds the best part of a long text for search results. " +
+		"The last sentence has nothing interesting."
+	ctx := pongo2.Context{"text": text}
+
+	c.Assert(getResult("{{ text|excerpt:'excerpt search,10' }}", ctx), Equals,
+		"…example the <mark>excerpt</mark> filter finds the best part of a…")
+	c.Assert(getResult("{{ text|excerpt:'django,8' }}", ctx), Equals,
+		"…inspired by <mark>Django</mark>. Templates are compiled once and…")
+	c.Assert(getResult("{{ text|excerpt:'templat,6' }}", ctx), Equals,
+		"…a <mark>template</mark> engine. It is written…")
+	c.Assert(getResult("{{ text|excerpt:'interesting,6' }}", ctx), Equals,
+		"…The last sentence has nothing <mark>interesting</mark>.")
+
+	// no matches, the beginning of the text
+	c.Assert(getResult("{{ text|excerpt:'python,5' }}", ctx), Equals, "Pongo2 is a template engine…")
+	c.Assert(getResult("{{ text|excerpt:'go' }}", pongo2.Context{"text": "Short text about Go."}), Equals,
+		"Short text about <mark>Go</mark>.")
+	c.Assert(getResult("{{ text|excerpt:'go' }}", pongo2.Context{"text": ""}), Equals, "")
+
+	// HTML input is escaped and the markup is dropped
+	html := `<h1>Title</h1><p>The <b>quick</b> brown fox &amp; the lazy <i>dog</i> &lt;3</p><p>Another paragraph.</p>`
+	c.Assert(getResult("{{ text|excerpt:'fox,4' }}", pongo2.Context{"text": html}), Equals,
+		"…brown <mark>fox</mark> &amp; the lazy…")
+	c.Assert(getResult("{{ text|excerpt:'dog another' }}", pongo2.Context{"text": html}), Equals,
+		"Title The quick brown fox &amp; the lazy <mark>dog</mark> &lt;3 <mark>Another</mark> paragraph.")
+}
+
+func (s *TestSuite1) TestExcerptBestWindow(c *C) {
+	// terms leaving the window are not counted any more
+	c.Assert(excerptBestWindow([]int{0, 0, 0, 1, -1, -1}, 2, 2), Equals, 2)
+	c.Assert(excerptBestWindow([]int{0, -1, 0, -1, -1, 1, 0, -1}, 2, 3), Equals, 5)
+	c.Assert(excerptBestWindow([]int{-1, 1, 1, -1, 0, 1, -1, -1}, 2, 4), Equals, 1)
+	c.Assert(excerptBestWindow([]int{-1, -1, -1}, 1, 2), Equals, 0)
+}
+
+func (s *TestSuite1) TestFilterReadingTime(c *C) {
+	words := strings.Repeat("word ", 450)
+	c.Assert(getResult("{{ text|readingtime }}", pongo2.Context{"text": words}), Equals, "3")
+	c.Assert(getResult("{{ text|readingtime:300 }}", pongo2.Context{"text": words}), Equals, "2")
+	c.Assert(getResult("{{ text|readingtime }}", pongo2.Context{"text": "<p>Just <b>a</b> few words.</p>"}), Equals, "1")
+	c.Assert(getResult("{{ text|readingtime }}", pongo2.Context{"text": "<p> </p>"}), Equals, "0")
+}
@@ -0,0 +1,105 @@
+package pongo2addons
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/flosch/pongo2/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatterDelimiters are the opening delimiters of the formats and the lines closing them.
+var frontMatterDelimiters = map[string][]string{
+	"---": {"---", "..."}, // YAML
+	"+++": {"+++"},        // TOML
+}
+
+// splitFrontMatter splits the document into the opening delimiter, the front matter and the body.
+// The delimiter is empty if the document doesn't start with front matter.
+func splitFrontMatter(source string) (delimiter string, meta string, body string) {
+	s := strings.TrimPrefix(source, "\ufeff")
+
+	firstLine, rest, found := strings.Cut(s, "\n")
+	delimiter = strings.TrimRight(firstLine, " \t\r")
+	closing, ok := frontMatterDelimiters[delimiter]
+	if !found || !ok {
+		return "", "", source
+	}
+
+	for start := 0; start < len(rest); {
+		end := strings.IndexByte(rest[start:], '\n')
+		if end < 0 {
+			end = len(rest)
+		} else {
+			end += start
+		}
+
+		line := strings.TrimRight(rest[start:end], " \t\r")
+		for _, c := range closing {
+			if line == c {
+				return delimiter, rest[:start], rest[min(end+1, len(rest)):]
+			}
+		}
+		start = end + 1
+	}
+
+	return "", "", source
+}
+
+// parseFrontMatter decodes the YAML ("---") or TOML ("+++") front matter into a map.
+func parseFrontMatter(delimiter string, meta string) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+
+	switch delimiter {
+	case "---":
+		if err := yaml.Unmarshal([]byte(meta), &out); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+		if out == nil {
+			// the front matter was empty
+			out = map[string]interface{}{}
+		}
+	case "+++":
+		if err := toml.Unmarshal([]byte(meta), &out); err != nil {
+			return nil, fmt.Errorf("front matter: %w", err)
+		}
+	default:
+		return nil, errors.New("no front matter")
+	}
+
+	return out, nil
+}
+
+// stripFrontMatter returns the document without its front matter.
+func stripFrontMatter(source string) string {
+	delimiter, _, body := splitFrontMatter(source)
+	if delimiter == "" {
+		return source
+	}
+
+	return body
+}
+
+// filterMarkdownFrontMatter returns the YAML ("---") or TOML ("+++") front matter of the document as
+// a map, it is empty if there is none. Render the body with markdown:"strip_frontmatter":
+//
+//	{% with page=content|markdown_frontmatter %}<h1>{{ page.title }}</h1>{% endwith %}
+//	{{ content|markdown:"strip_frontmatter" }}
+func filterMarkdownFrontMatter(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	delimiter, meta, _ := splitFrontMatter(in.String())
+	if delimiter == "" {
+		return pongo2.AsValue(map[string]interface{}{}), nil
+	}
+
+	out, err := parseFrontMatter(delimiter, meta)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:markdown_frontmatter",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(out), nil
+}
@@ -0,0 +1,38 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterMarkdownFrontMatter(c *C) {
+	ctx := pongo2.Context{
+		"yaml":  "---\ntitle: Hello <World>\ntags: [go, pongo2]\nauthor:\n  name: Ann\n---\n# Body\n",
+		"toml":  "+++\r\ntitle = \"TOML page\"\r\n[author]\r\nname = \"Bob\"\r\n+++\r\nText\n",
+		"dots":  "\ufeff--- \ntitle: Dots\n...\nText\n",
+		"empty": "---\n---\nText\n",
+		"plain": "Text\n\n---\n\nmore\n",
+		"open":  "---\ntitle: never closed\n",
+		"bad":   "---\ntitle: [\n---\nText\n",
+	}
+
+	tpl := `{% with page=yaml|markdown_frontmatter %}<h1>{{ page.title }}</h1>{{ page.tags|join:"," }} {{ page.author.name }}{% endwith %}`
+	c.Assert(getResult(tpl, ctx), Equals, "<h1>Hello &lt;World&gt;</h1>go,pongo2 Ann")
+	c.Assert(getResult(`{% with page=toml|markdown_frontmatter %}{{ page.title }} {{ page.author.name }}{% endwith %}`, ctx),
+		Equals, "TOML page Bob")
+	c.Assert(getResult(`{% with page=dots|markdown_frontmatter %}{{ page.title }}{% endwith %}`, ctx), Equals, "Dots")
+	c.Assert(getResult(`{{ empty|markdown_frontmatter|length }}`, ctx), Equals, "0")
+	c.Assert(getResult(`{{ plain|markdown_frontmatter|length }}`, ctx), Equals, "0")
+	c.Assert(getResult(`{{ open|markdown_frontmatter|length }}`, ctx), Equals, "0")
+
+	// the body
+	c.Assert(getResult(`{{ yaml|markdown:"strip_frontmatter" }}`, ctx), Equals, "<h1>Body</h1>\n")
+	c.Assert(getResult(`{{ toml|markdown:"strip_frontmatter" }}`, ctx), Equals, "<p>Text</p>\n")
+	c.Assert(getResult(`{{ dots|markdown:"strip_frontmatter" }}`, ctx), Equals, "<p>Text</p>\n")
+	c.Assert(getResult(`{{ plain|markdown:"strip_frontmatter" }}`, ctx), Equals, "<p>Text</p>\n\n<hr />\n\n<p>more</p>\n")
+	c.Assert(getResult(`{{ yaml|markdown }}`, ctx), Not(Equals), "<h1>Body</h1>\n")
+
+	// errors
+	c.Assert(getResult(`{{ bad|markdown_frontmatter }}`, ctx), Equals, "")
+}
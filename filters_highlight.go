@@ -0,0 +1,123 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/flosch/pongo2/v6"
+)
+
+// highlightDefaultStyle is the chroma style used unless SetHighlightStyle changes it.
+const highlightDefaultStyle = "github"
+
+var (
+	highlightMu sync.RWMutex
+
+	highlightStyle   = highlightDefaultStyle
+	highlightClasses = false
+)
+
+// SetHighlightStyle selects the chroma style (theme) of the highlighted code, "github" by default.
+// The names are listed by styles.Names() of github.com/alecthomas/chroma/styles.
+func SetHighlightStyle(name string) error {
+	if _, ok := styles.Registry[name]; !ok {
+		return fmt.Errorf("highlight style %q is not registered", name)
+	}
+
+	highlightMu.Lock()
+	defer highlightMu.Unlock()
+	highlightStyle = name
+
+	return nil
+}
+
+// SetHighlightClasses switches the highlighted code from inline styles to CSS classes,
+// the stylesheet of the style is returned by HighlightCSS.
+func SetHighlightClasses(classes bool) {
+	highlightMu.Lock()
+	defer highlightMu.Unlock()
+
+	highlightClasses = classes
+}
+
+// highlightSettings returns the current style and mode.
+func highlightSettings() (*chroma.Style, bool, string) {
+	highlightMu.RLock()
+	defer highlightMu.RUnlock()
+
+	return styles.Get(highlightStyle), highlightClasses, highlightStyle + "," + strconv.FormatBool(highlightClasses)
+}
+
+// HighlightCSS returns the stylesheet of the current style for the CSS classes mode.
+func HighlightCSS() (string, error) {
+	style, _, _ := highlightSettings()
+
+	var b strings.Builder
+	if err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&b, style); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// highlightLexer returns the lexer of the language name, alias or file extension, or nil.
+func highlightLexer(lang string) chroma.Lexer {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return nil
+	}
+	if lexer := lexers.Get(lang); lexer != nil {
+		return lexer
+	}
+
+	return lexers.Match("file." + lang)
+}
+
+// highlightCode writes the code highlighted with the lexer as HTML with the current settings.
+func highlightCode(b *strings.Builder, code string, lexer chroma.Lexer) error {
+	style, classes, _ := highlightSettings()
+
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return err
+	}
+
+	return chromahtml.New(chromahtml.WithClasses(classes)).Format(b, style, iterator)
+}
+
+// filterHighlight highlights the code of the language as HTML: {{ source|highlight:"go" }}. Without the
+// language it is guessed from the code. Code blocks of the markdown filter are highlighted the same way
+// with its "highlight" option.
+func filterHighlight(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	code := in.String()
+
+	lexer := highlightLexer(param.String())
+	switch {
+	case lexer != nil:
+	case strings.TrimSpace(param.String()) != "":
+		return nil, &pongo2.Error{
+			Sender:    "filter:highlight",
+			OrigError: fmt.Errorf("unknown language %q", param.String()),
+		}
+	default:
+		if lexer = lexers.Analyse(code); lexer == nil {
+			lexer = lexers.Fallback
+		}
+	}
+
+	var b strings.Builder
+	if err := highlightCode(&b, code, lexer); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:highlight",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsSafeValue(b.String()), nil
+}
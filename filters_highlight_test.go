@@ -0,0 +1,81 @@
+package pongo2addons
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterHighlight(c *C) {
+	defer func() {
+		c.Assert(SetHighlightStyle(highlightDefaultStyle), IsNil)
+		SetHighlightClasses(false)
+	}()
+
+	ctx := pongo2.Context{"code": `x := "<a>"`, "py": "def f(): pass", "sh": "#!/bin/sh\necho hi"}
+
+	// inline styles by default, the code is escaped
+	c.Assert(getResult(`{{ code|highlight:"go" }}`, ctx), Equals, `<pre tabindex="0" style="background-color:#fff;"><code>`+
+		`<span style="display:flex;"><span>x <span style="color:#000;font-weight:bold">:=</span> `+
+		`<span style="color:#d14">&#34;&lt;a&gt;&#34;</span></span></span></code></pre>`)
+
+	// CSS classes, languages by file extension
+	SetHighlightClasses(true)
+	c.Assert(getResult(`{{ py|highlight:"py" }}`, ctx), Equals, `<pre tabindex="0" class="chroma"><code>`+
+		`<span class="line"><span class="cl"><span class="k">def</span> <span class="nf">f</span><span class="p">():</span> `+
+		`<span class="k">pass</span></span></span></code></pre>`)
+	css, err := HighlightCSS()
+	c.Assert(err, IsNil)
+	c.Assert(strings.Contains(css, ".chroma .k {"), Equals, true)
+
+	// other styles
+	c.Assert(SetHighlightStyle("monokai"), IsNil)
+	monokai, err := HighlightCSS()
+	c.Assert(err, IsNil)
+	c.Assert(monokai, Not(Equals), css)
+	c.Assert(SetHighlightStyle("no-such-style"), ErrorMatches, `highlight style "no-such-style" is not registered`)
+
+	// without the language it is guessed
+	c.Assert(strings.HasPrefix(getResult(`{{ sh|highlight }}`, ctx), `<pre tabindex="0" class="chroma">`), Equals, true)
+
+	// errors
+	c.Assert(getResult(`{{ code|highlight:"no-such-language" }}`, ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterMarkdownHighlight(c *C) {
+	defer SetHighlightClasses(false)
+	SetHighlightClasses(true)
+
+	ctx := pongo2.Context{"doc": "# Code\n\n```go\nx := 1\n```\n\n```nope\na<b\n```\n\n    indented\n"}
+	c.Assert(getResult(`{{ doc|markdown:"highlight" }}`, ctx), Equals, "<h1>Code</h1>\n\n"+
+		`<pre tabindex="0" class="chroma"><code><span class="line"><span class="cl"><span class="nx">x</span> <span class="o">:=</span> <span class="mi">1</span>`+"\n"+
+		"</span></span></code></pre>\n\n"+
+		"<pre><code class=\"language-nope\">a&lt;b\n</code></pre>\n\n"+
+		"<pre><code>indented\n</code></pre>\n")
+
+	// highlighting is off by default
+	c.Assert(getResult(`{{ doc|markdown }}`, ctx), Equals, "<h1>Code</h1>\n\n"+
+		"<pre><code class=\"language-go\">x := 1\n</code></pre>\n\n"+
+		"<pre><code class=\"language-nope\">a&lt;b\n</code></pre>\n\n"+
+		"<pre><code>indented\n</code></pre>\n")
+
+	// the option changes the code blocks only, not the line breaks around them
+	pre := regexp.MustCompile(`(?s)<pre.*?</pre>`)
+	for _, doc := range []string{"```go\nx := 1\n```\ntext\n", "- item\n\n    ```go\n    x := 1\n    ```\n- next\n"} {
+		ctx := pongo2.Context{"doc": doc}
+		plain, highlighted := getResult(`{{ doc|markdown }}`, ctx), getResult(`{{ doc|markdown:"highlight" }}`, ctx)
+		c.Assert(highlighted, Not(Equals), plain)
+		c.Assert(pre.ReplaceAllString(highlighted, "<pre/>"), Equals, pre.ReplaceAllString(plain, "<pre/>"))
+	}
+
+	// the cache keeps the output of the settings
+	defer SetMarkdownCacheSize(0)
+	SetMarkdownCacheSize(1 << 20)
+	classes := getResult(`{{ doc|markdown:"highlight" }}`, ctx)
+	SetHighlightClasses(false)
+	c.Assert(getResult(`{{ doc|markdown:"highlight" }}`, ctx), Not(Equals), classes)
+	c.Assert(GetMarkdownCacheStats().Misses, Equals, uint64(2))
+}
@@ -0,0 +1,193 @@
+package pongo2addons
+
+import (
+	"errors"
+	"html"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// defaultJoinLanguage is used when join_natural gets no language or an unknown one.
+const defaultJoinLanguage = "en"
+
+// joinConjunctions are the words join_natural puts before the last item.
+type joinConjunctions struct {
+	and, or string
+}
+
+var (
+	joinConjunctionsMu sync.RWMutex
+
+	joinConjunctionsByLanguage = map[string]joinConjunctions{}
+)
+
+func init() {
+	RegisterJoinConjunctions("en", "and", "or")
+	RegisterJoinConjunctions("de", "und", "oder")
+	RegisterJoinConjunctions("fr", "et", "ou")
+	RegisterJoinConjunctions("es", "y", "o")
+	RegisterJoinConjunctions("it", "e", "o")
+	RegisterJoinConjunctions("pt", "e", "ou")
+	RegisterJoinConjunctions("nl", "en", "of")
+	RegisterJoinConjunctions("ru", "и", "или")
+}
+
+// RegisterJoinConjunctions sets the "and" and "or" words of the language for the join_natural filter.
+func RegisterJoinConjunctions(lang string, and string, or string) {
+	joinConjunctionsMu.Lock()
+	defer joinConjunctionsMu.Unlock()
+
+	joinConjunctionsByLanguage[strings.ToLower(lang)] = joinConjunctions{and: and, or: or}
+}
+
+// joinConjunction returns the conjunction of the language put before the last item.
+func joinConjunction(lang string, or bool, last string) string {
+	joinConjunctionsMu.RLock()
+	defer joinConjunctionsMu.RUnlock()
+
+	lang = strings.ToLower(lang)
+	conj, ok := joinConjunctionsByLanguage[lang]
+	if !ok {
+		lang = defaultJoinLanguage
+		conj = joinConjunctionsByLanguage[lang]
+	}
+
+	word := conj.and
+	if or {
+		word = conj.or
+	}
+
+	// Spanish "y" and "o" change before the same sounds: "padres e hijos", "siete u ocho"
+	if lang == "es" {
+		next := strings.ToLower(last)
+		switch {
+		case word == "y" && (strings.HasPrefix(next, "i") || strings.HasPrefix(next, "hi")) && !strings.HasPrefix(next, "hie"):
+			word = "e"
+		case word == "o" && (strings.HasPrefix(next, "o") || strings.HasPrefix(next, "ho")):
+			word = "u"
+		}
+	}
+
+	return word
+}
+
+// joinItems returns the items of a slice value as strings, other values are a single item.
+func joinItems(in *pongo2.Value) []string {
+	if in.IsNil() {
+		return nil
+	}
+	if !in.CanSlice() || in.IsString() {
+		return []string{in.String()}
+	}
+
+	items := make([]string, 0, in.Len())
+	for i := 0; i < in.Len(); i++ {
+		items = append(items, in.Index(i).String())
+	}
+
+	return items
+}
+
+// filterJoinHTML escapes the items and joins them with the separator, "<br />" by default,
+// which is kept as HTML:
+//
+//	{{ lines|join_html }}
+//	{{ tags|join_html:"</li><li>" }}
+func filterJoinHTML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	separator := "<br />"
+	if !param.IsNil() {
+		separator = param.String()
+	}
+
+	items := joinItems(in)
+	for i := range items {
+		items[i] = html.EscapeString(items[i])
+	}
+
+	return pongo2.AsSafeValue(strings.Join(items, separator)), nil
+}
+
+// filterJoinNatural joins the items as a human readable list, "a, b and c":
+//
+//	{{ names|join_natural }}
+//	{{ names|join_natural:"en,or,oxford" }}
+//	{{ names|join_natural:"de" }}
+//
+// The first option is the language of the conjunction, "or" selects the disjunction and
+// "oxford" puts a serial comma before it.
+func filterJoinNatural(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	lang := defaultJoinLanguage
+	or, oxford := false, false
+	for i, option := range strings.Split(param.String(), ",") {
+		option = strings.TrimSpace(option)
+		switch {
+		case option == "or":
+			or = true
+		case option == "oxford":
+			oxford = true
+		case i == 0 && option != "":
+			lang = option
+		}
+	}
+
+	items := joinItems(in)
+	switch len(items) {
+	case 0:
+		return pongo2.AsValue(""), nil
+	case 1:
+		return pongo2.AsValue(items[0]), nil
+	}
+
+	last := items[len(items)-1]
+	conj := joinConjunction(lang, or, strings.TrimLeftFunc(last, unicode.IsSpace))
+
+	head := strings.Join(items[:len(items)-1], ", ")
+	if oxford && len(items) > 2 {
+		head += ","
+	}
+
+	return pongo2.AsValue(head + " " + conj + " " + last), nil
+}
+
+// filterJoinAttr joins the field of every item, the items are maps or structs:
+//
+//	{{ users|join_attr:"Name" }}
+//	{{ posts|join_attr:"author.name,; " }}
+//
+// Struct fields match by name or json tag, dots select nested fields. The separator
+// follows the first comma, ", " by default. Items without the field are skipped.
+func filterJoinAttr(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	parts := strings.SplitN(param.String(), ",", 2)
+	field := strings.TrimSpace(parts[0])
+	separator := ", "
+	if len(parts) > 1 {
+		separator = parts[1]
+	}
+
+	if field == "" {
+		return nil, &pongo2.Error{
+			Sender:    "filter:join_attr",
+			OrigError: errors.New("field name is empty"),
+		}
+	}
+	if !in.CanSlice() || in.IsString() {
+		return nil, &pongo2.Error{
+			Sender:    "filter:join_attr",
+			OrigError: errors.New("input is not sliceable"),
+		}
+	}
+
+	var items []string
+	for i := 0; i < in.Len(); i++ {
+		value, ok := queryFieldPath(in.Index(i).Interface(), field)
+		if !ok || value == nil {
+			continue
+		}
+		items = append(items, pongo2.AsValue(value).String())
+	}
+
+	return pongo2.AsValue(strings.Join(items, separator)), nil
+}
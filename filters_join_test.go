@@ -0,0 +1,69 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterJoinHTML(c *C) {
+	ctx := pongo2.Context{"lines": []string{"a < b", "c & d"}}
+	c.Assert(getResult("{{ lines|join_html }}", ctx), Equals, "a &lt; b<br />c &amp; d")
+	c.Assert(getResult("<li>{{ lines|join_html:'</li><li>' }}</li>", ctx), Equals, "<li>a &lt; b</li><li>c &amp; d</li>")
+	c.Assert(getResult("{{ lines|join_html:'' }}", ctx), Equals, "a &lt; bc &amp; d")
+	c.Assert(getResult("{{ 'x<y'|join_html }}", ctx), Equals, "x&lt;y")
+}
+
+func (s *TestSuite1) TestFilterJoinNatural(c *C) {
+	ctx := pongo2.Context{
+		"none":  []string{},
+		"one":   []string{"tea"},
+		"two":   []string{"tea", "coffee"},
+		"three": []interface{}{"tea", "coffee", 3},
+		"es":    []string{"padres", "hijos"},
+		"esor":  []string{"siete", "ocho"},
+	}
+
+	c.Assert(getResult("{{ none|join_natural }}", ctx), Equals, "")
+	c.Assert(getResult("{{ one|join_natural }}", ctx), Equals, "tea")
+	c.Assert(getResult("{{ two|join_natural }}", ctx), Equals, "tea and coffee")
+	c.Assert(getResult("{{ three|join_natural }}", ctx), Equals, "tea, coffee and 3")
+	c.Assert(getResult("{{ three|join_natural:'en,oxford' }}", ctx), Equals, "tea, coffee, and 3")
+	c.Assert(getResult("{{ three|join_natural:'en,or,oxford' }}", ctx), Equals, "tea, coffee, or 3")
+	c.Assert(getResult("{{ two|join_natural:'oxford' }}", ctx), Equals, "tea and coffee")
+	c.Assert(getResult("{{ three|join_natural:'de' }}", ctx), Equals, "tea, coffee und 3")
+	c.Assert(getResult("{{ two|join_natural:'ru,or' }}", ctx), Equals, "tea или coffee")
+	c.Assert(getResult("{{ two|join_natural:'xx' }}", ctx), Equals, "tea and coffee")
+	c.Assert(getResult("{{ es|join_natural:'es' }}", ctx), Equals, "padres e hijos")
+	c.Assert(getResult("{{ esor|join_natural:'es,or' }}", ctx), Equals, "siete u ocho")
+}
+
+type joinUser struct {
+	Name    string `json:"name"`
+	Profile *joinProfile
+}
+
+type joinProfile struct {
+	City string `json:"city"`
+}
+
+func (s *TestSuite1) TestFilterJoinAttr(c *C) {
+	ctx := pongo2.Context{
+		"users": []*joinUser{
+			{Name: "Ann", Profile: &joinProfile{City: "Oslo"}},
+			{Name: "Bob"},
+			{Name: "Cid", Profile: &joinProfile{City: "Rome"}},
+		},
+		"maps": []map[string]interface{}{{"id": 1}, {"id": 2}, {"other": 3}},
+	}
+
+	c.Assert(getResult("{{ users|join_attr:'Name' }}", ctx), Equals, "Ann, Bob, Cid")
+	c.Assert(getResult("{{ users|join_attr:'name,; ' }}", ctx), Equals, "Ann; Bob; Cid")
+	c.Assert(getResult("{{ users|join_attr:'name,/' }}", ctx), Equals, "Ann/Bob/Cid")
+	c.Assert(getResult("{{ users|join_attr:'Profile.city' }}", ctx), Equals, "Oslo, Rome")
+	c.Assert(getResult("{{ maps|join_attr:'id,-' }}", ctx), Equals, "1-2")
+
+	// errors
+	c.Assert(getResult("{{ users|join_attr:'' }}", ctx), Equals, "")
+	c.Assert(getResult("{{ 'abc'|join_attr:'id' }}", ctx), Equals, "")
+}
@@ -0,0 +1,237 @@
+package pongo2addons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/russross/blackfriday/v2"
+)
+
+var (
+	markdownCacheMu sync.RWMutex
+
+	// markdownCache keeps the rendered HTML by the hash of the source, it is nil until enabled.
+	markdownCache *lruCache
+
+	markdownCacheHits   uint64
+	markdownCacheMisses uint64
+)
+
+// MarkdownCacheStats are the counters of the markdown cache.
+type MarkdownCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Entries is the number of cached documents, Bytes the size of their HTML.
+	Entries int
+	Bytes   int
+}
+
+// SetMarkdownCacheSize enables the cache of the markdown filter. The rendered HTML is kept by the
+// hash of the source up to maxBytes in total, the least recently used documents are evicted.
+// 0 or less disables the cache. The cache is emptied and the counters are reset.
+func SetMarkdownCacheSize(maxBytes int) {
+	markdownCacheMu.Lock()
+	defer markdownCacheMu.Unlock()
+
+	markdownCache = nil
+	if maxBytes > 0 {
+		markdownCache = newSizedLRUCache(maxBytes)
+	}
+	atomic.StoreUint64(&markdownCacheHits, 0)
+	atomic.StoreUint64(&markdownCacheMisses, 0)
+}
+
+// GetMarkdownCacheStats returns the hit and miss counters and the size of the markdown cache.
+func GetMarkdownCacheStats() MarkdownCacheStats {
+	markdownCacheMu.RLock()
+	defer markdownCacheMu.RUnlock()
+
+	stats := MarkdownCacheStats{
+		Hits:   atomic.LoadUint64(&markdownCacheHits),
+		Misses: atomic.LoadUint64(&markdownCacheMisses),
+	}
+	if markdownCache != nil {
+		stats.Entries = markdownCache.count()
+		stats.Bytes = markdownCache.usedBytes()
+	}
+
+	return stats
+}
+
+// markdownRenderer is the blackfriday HTML renderer highlighting the fenced code blocks
+// of known languages if asked to, see SetHighlightStyle and SetHighlightClasses, and rewriting the links.
+type markdownRenderer struct {
+	*blackfriday.HTMLRenderer
+	links     *markdownLinks
+	highlight bool
+}
+
+func newMarkdownRenderer(links *markdownLinks, highlight bool) *markdownRenderer {
+	return &markdownRenderer{
+		HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: blackfriday.CommonHTMLFlags}),
+		links:        links,
+		highlight:    highlight,
+	}
+}
+
+// RenderNode highlights the code blocks and rewrites the links and images, all other nodes
+// are rendered by the HTML renderer.
+func (r *markdownRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	if r.links != nil && r.links.renderLink(w, node, entering) {
+		return blackfriday.GoToNext
+	}
+
+	if r.highlight && node.Type == blackfriday.CodeBlock {
+		// the info string is "go" or "go {.class}", its first word is the language
+		if info := strings.Fields(string(node.Info)); len(info) > 0 {
+			if lexer := highlightLexer(info[0]); lexer != nil {
+				var b strings.Builder
+				if err := highlightCode(&b, string(node.Literal), lexer); err == nil {
+					// only the <pre> element is replaced, the line breaks around the block are kept
+					var block bytes.Buffer
+					status := r.HTMLRenderer.RenderNode(&block, node, entering)
+					html := block.String()
+					start, end := strings.Index(html, "<pre>"), strings.LastIndex(html, "</pre>")
+					if start >= 0 && end >= start {
+						html = html[:start] + strings.TrimSuffix(b.String(), "\n") + html[end+len("</pre>"):]
+					}
+					_, _ = io.WriteString(w, html)
+					return status
+				}
+			}
+		}
+	}
+
+	return r.HTMLRenderer.RenderNode(w, node, entering)
+}
+
+func runMarkdown(source string, links *markdownLinks, highlight bool) string {
+	return string(blackfriday.Run([]byte(source), blackfriday.WithRenderer(newMarkdownRenderer(links, highlight))))
+}
+
+// renderMarkdown renders the source with blackfriday, through the cache if it is enabled.
+func renderMarkdown(source string, links *markdownLinks, highlight bool) string {
+	markdownCacheMu.RLock()
+	cache := markdownCache
+	markdownCacheMu.RUnlock()
+
+	if cache == nil {
+		return runMarkdown(source, links, highlight)
+	}
+
+	// the highlight settings and the link options change the output as well
+	settings := ""
+	if highlight {
+		_, _, settings = highlightSettings()
+	}
+	if links != nil {
+		settings += "\x00" + links.key
+	}
+	sum := sha256.Sum256([]byte(settings + "\x00" + source))
+	key := hex.EncodeToString(sum[:])
+	if out, ok := cache.get(key); ok {
+		atomic.AddUint64(&markdownCacheHits, 1)
+		return out.(string)
+	}
+	atomic.AddUint64(&markdownCacheMisses, 1)
+
+	out := runMarkdown(source, links, highlight)
+	cache.addSized(key, out, len(key)+len(out))
+
+	return out
+}
+
+// markdownOptions are parsed from the markdown parameter.
+type markdownOptions struct {
+	// stripFrontMatter removes the YAML or TOML front matter before rendering, see markdown_frontmatter.
+	stripFrontMatter bool
+	// highlight highlights the fenced code blocks of known languages like the highlight filter.
+	highlight bool
+	// links rewrite the links and images, nil keeps them.
+	links *markdownLinks
+}
+
+// markdownParams parses the comma separated options of the markdown filter:
+// "strip_frontmatter,highlight,links=name,base=https://example.com/docs/,external". The base URL and
+// external override the options registered with RegisterMarkdownLinks.
+func markdownParams(param *pongo2.Value) (markdownOptions, error) {
+	var opts markdownOptions
+	var base, external string
+	for _, option := range strings.Split(param.String(), ",") {
+		key, value := strings.TrimSpace(option), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+		}
+
+		switch key {
+		case "strip_frontmatter":
+			opts.stripFrontMatter = true
+		case "highlight":
+			opts.highlight = true
+		case "links":
+			links, ok := registeredMarkdownLinks(value)
+			if !ok {
+				return opts, fmt.Errorf("markdown links %q are not registered", value)
+			}
+			opts.links = links
+		case "base":
+			base = value
+		case "external":
+			external = "external"
+		}
+	}
+
+	if base == "" && external == "" {
+		return opts, nil
+	}
+
+	links := &markdownLinks{}
+	if opts.links != nil {
+		*links = *opts.links
+	}
+	if base != "" {
+		u, err := url.Parse(base)
+		if err != nil {
+			return opts, fmt.Errorf("base URL: %w", err)
+		}
+		links.base = u
+	}
+	links.external = links.external || external != ""
+	links.key += "\x00" + base + "\x00" + external
+	opts.links = links
+
+	return opts, nil
+}
+
+// filterMarkdown renders the markdown text to HTML. The fenced code blocks of known languages are highlighted
+// with {{ content|markdown:"highlight" }}. Enable the cache of the rendered documents with SetMarkdownCacheSize.
+// The front matter of content files is removed with {{ content|markdown:"strip_frontmatter" }}.
+// The links and images are rewritten with the options registered with RegisterMarkdownLinks
+// or given in the parameter:
+//
+//	{{ page|markdown:"links=docs" }}
+//	{{ page|markdown:"base=https://example.com/docs/,external" }}
+func filterMarkdown(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts, err := markdownParams(param)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:markdown",
+			OrigError: err,
+		}
+	}
+
+	source := in.String()
+	if opts.stripFrontMatter {
+		source = stripFrontMatter(source)
+	}
+
+	return pongo2.AsSafeValue(renderMarkdown(source, opts.links, opts.highlight)), nil
+}
@@ -0,0 +1,134 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/russross/blackfriday/v2"
+)
+
+// MarkdownLinks rewrite the links and images of the markdown filter, register them by name
+// with RegisterMarkdownLinks.
+type MarkdownLinks struct {
+	// BaseURL resolves the relative URLs of links and images, like "https://example.com/docs/".
+	BaseURL string
+	// ExternalTargetBlank adds target="_blank" rel="noopener" to the links to other hosts.
+	ExternalTargetBlank bool
+	// ImageURL rewrites the URLs of the images after resolving them, e.g. to prefix a CDN.
+	ImageURL func(url string) string
+}
+
+// markdownLinks are the parsed MarkdownLinks.
+type markdownLinks struct {
+	base     *url.URL
+	external bool
+	imageURL func(string) string
+	// key identifies the options in the cache of the rendered documents.
+	key string
+}
+
+var (
+	markdownLinksMu sync.RWMutex
+
+	markdownLinksRegistry = map[string]*markdownLinks{}
+	// markdownLinksGeneration changes with every registration, the cached documents of
+	// replaced options are not used anymore.
+	markdownLinksGeneration int
+)
+
+// RegisterMarkdownLinks makes the link options available to the markdown filter by name:
+//
+//	pongo2addons.RegisterMarkdownLinks("docs", pongo2addons.MarkdownLinks{
+//		BaseURL:             "https://example.com/docs/",
+//		ExternalTargetBlank: true,
+//		ImageURL:            func(u string) string { return "https://cdn.example.com/?src=" + url.QueryEscape(u) },
+//	})
+//
+//	{{ page|markdown:"links=docs" }}
+func RegisterMarkdownLinks(name string, links MarkdownLinks) error {
+	parsed := &markdownLinks{external: links.ExternalTargetBlank, imageURL: links.ImageURL}
+	if links.BaseURL != "" {
+		base, err := url.Parse(links.BaseURL)
+		if err != nil {
+			return fmt.Errorf("base URL: %w", err)
+		}
+		parsed.base = base
+	}
+
+	markdownLinksMu.Lock()
+	defer markdownLinksMu.Unlock()
+
+	markdownLinksGeneration++
+	parsed.key = name + "#" + strconv.Itoa(markdownLinksGeneration)
+	markdownLinksRegistry[name] = parsed
+
+	return nil
+}
+
+func registeredMarkdownLinks(name string) (*markdownLinks, bool) {
+	markdownLinksMu.RLock()
+	defer markdownLinksMu.RUnlock()
+
+	links, ok := markdownLinksRegistry[name]
+	return links, ok
+}
+
+// resolve returns the URL resolved against the base URL, fragments and unparsable URLs are kept.
+func (l *markdownLinks) resolve(link string) string {
+	if l.base == nil || link == "" || strings.HasPrefix(link, "#") {
+		return link
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+
+	return l.base.ResolveReference(u).String()
+}
+
+// isExternal reports whether the link goes to another host than the base URL, any host without it.
+func (l *markdownLinks) isExternal(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" || u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	return l.base == nil || !strings.EqualFold(u.Host, l.base.Host)
+}
+
+// renderLink rewrites the link or image node, it returns true if the link is written already.
+func (l *markdownLinks) renderLink(w io.Writer, node *blackfriday.Node, entering bool) bool {
+	switch {
+	case node.Type == blackfriday.Image && entering:
+		node.Destination = []byte(l.resolve(string(node.Destination)))
+		if l.imageURL != nil {
+			node.Destination = []byte(l.imageURL(string(node.Destination)))
+		}
+	case node.Type == blackfriday.Link && node.NoteID == 0:
+		if entering {
+			node.Destination = []byte(l.resolve(string(node.Destination)))
+		}
+		if !l.external || !l.isExternal(string(node.Destination)) {
+			return false
+		}
+
+		if !entering {
+			_, _ = io.WriteString(w, "</a>")
+			return true
+		}
+		_, _ = io.WriteString(w, `<a href="`+html.EscapeString(string(node.Destination))+`"`)
+		if len(node.Title) > 0 {
+			_, _ = io.WriteString(w, ` title="`+html.EscapeString(string(node.Title))+`"`)
+		}
+		_, _ = io.WriteString(w, ` target="_blank" rel="noopener">`)
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,59 @@
+package pongo2addons
+
+import (
+	"net/url"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterMarkdownLinks(c *C) {
+	ctx := pongo2.Context{
+		"doc": "[Guide](guide/intro.md \"Intro\") [Top](/) [Up](../faq) [Here](#usage) " +
+			"[Go](https://go.dev/doc) [Self](https://example.com/blog) [Mail](mailto:a@example.com)\n\n" +
+			"![Logo](img/logo.png)",
+		"cdn": "[a](b) [c](https://go.dev/?a=1&b=2 \"T\")\n\n![i](i.png)",
+	}
+
+	// per call
+	c.Assert(getResult(`{{ doc|markdown:"base=https://example.com/docs/v1/" }}`, ctx), Equals,
+		`<p><a href="https://example.com/docs/v1/guide/intro.md" title="Intro">Guide</a> `+
+			`<a href="https://example.com/">Top</a> <a href="https://example.com/docs/faq">Up</a> `+
+			`<a href="#usage">Here</a> <a href="https://go.dev/doc">Go</a> <a href="https://example.com/blog">Self</a> `+
+			`<a href="mailto:a@example.com">Mail</a></p>`+"\n\n"+
+			`<p><img src="https://example.com/docs/v1/img/logo.png" alt="Logo" /></p>`+"\n")
+	c.Assert(getResult(`{{ doc|markdown:"base=https://example.com/docs/v1/,external" }}`, ctx), Equals,
+		`<p><a href="https://example.com/docs/v1/guide/intro.md" title="Intro">Guide</a> `+
+			`<a href="https://example.com/">Top</a> <a href="https://example.com/docs/faq">Up</a> `+
+			`<a href="#usage">Here</a> <a href="https://go.dev/doc" target="_blank" rel="noopener">Go</a> `+
+			`<a href="https://example.com/blog">Self</a> <a href="mailto:a@example.com">Mail</a></p>`+"\n\n"+
+			`<p><img src="https://example.com/docs/v1/img/logo.png" alt="Logo" /></p>`+"\n")
+
+	// registered, with the image URLs rewritten
+	c.Assert(RegisterMarkdownLinks("test-cdn", MarkdownLinks{
+		BaseURL:             "/docs/",
+		ExternalTargetBlank: true,
+		ImageURL: func(u string) string {
+			return "https://cdn.example.com/?src=" + url.QueryEscape(u)
+		},
+	}), IsNil)
+	c.Assert(getResult(`{{ cdn|markdown:"links=test-cdn" }}`, ctx), Equals,
+		`<p><a href="/docs/b">a</a> <a href="https://go.dev/?a=1&amp;b=2" title="T" target="_blank" rel="noopener">c</a></p>`+"\n\n"+
+			`<p><img src="https://cdn.example.com/?src=%2Fdocs%2Fi.png" alt="i" /></p>`+"\n")
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"links=test-cdn,base=/blog/" }}`, ctx), Equals, `<p><a href="/blog/b">a</a></p>`+"\n")
+
+	// the cache keeps the documents of the options apart
+	defer SetMarkdownCacheSize(0)
+	SetMarkdownCacheSize(1 << 20)
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"base=/x/" }}`, ctx), Equals, `<p><a href="/x/b">a</a></p>`+"\n")
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"base=/y/" }}`, ctx), Equals, `<p><a href="/y/b">a</a></p>`+"\n")
+	c.Assert(getResult(`{{ "[a](b)"|markdown }}`, ctx), Equals, `<p><a href="b">a</a></p>`+"\n")
+	c.Assert(RegisterMarkdownLinks("test-cdn", MarkdownLinks{BaseURL: "/v2/"}), IsNil)
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"links=test-cdn" }}`, ctx), Equals, `<p><a href="/v2/b">a</a></p>`+"\n")
+
+	// errors
+	c.Assert(RegisterMarkdownLinks("bad", MarkdownLinks{BaseURL: "http://[::1"}), NotNil)
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"links=unknown" }}`, ctx), Equals, "")
+	c.Assert(getResult(`{{ "[a](b)"|markdown:"base=http://[::1" }}`, ctx), Equals, "")
+}
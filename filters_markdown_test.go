@@ -0,0 +1,68 @@
+package pongo2addons
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterMarkdownCache(c *C) {
+	defer SetMarkdownCacheSize(0)
+
+	ctx := pongo2.Context{"a": "**a**", "b": "*b*"}
+
+	// disabled by default
+	c.Assert(getResult("{{ a|markdown }}", ctx), Equals, "<p><strong>a</strong></p>\n")
+	c.Assert(GetMarkdownCacheStats(), DeepEquals, MarkdownCacheStats{})
+
+	SetMarkdownCacheSize(1 << 20)
+	c.Assert(getResult("{{ a|markdown }}{{ a|markdown }}{{ b|markdown }}", ctx), Equals,
+		"<p><strong>a</strong></p>\n<p><strong>a</strong></p>\n<p><em>b</em></p>\n")
+
+	stats := GetMarkdownCacheStats()
+	c.Assert(stats.Hits, Equals, uint64(1))
+	c.Assert(stats.Misses, Equals, uint64(2))
+	c.Assert(stats.Entries, Equals, 2)
+	c.Assert(stats.Bytes, Equals, 2*64+len("<p><strong>a</strong></p>\n")+len("<p><em>b</em></p>\n"))
+
+	// bounded by bytes, the least recently used documents are evicted
+	SetMarkdownCacheSize(3 * 100)
+	for i := 0; i < 5; i++ {
+		renderMarkdown(strings.Repeat("x", i+1), nil, false)
+	}
+	stats = GetMarkdownCacheStats()
+	c.Assert(stats.Misses, Equals, uint64(5))
+	c.Assert(stats.Entries, Equals, 3)
+	c.Assert(stats.Bytes <= 300, Equals, true)
+	renderMarkdown("xxxxx", nil, false)
+	renderMarkdown("x", nil, false)
+	c.Assert(GetMarkdownCacheStats().Hits, Equals, uint64(1))
+
+	// documents larger than the cache are not kept
+	renderMarkdown(strings.Repeat("y", 500), nil, false)
+	c.Assert(GetMarkdownCacheStats().Entries, Equals, 3)
+
+	// concurrent renders
+	SetMarkdownCacheSize(1 << 20)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				renderMarkdown(strings.Repeat("z", j%5+1), nil, false)
+			}
+		}()
+	}
+	wg.Wait()
+	stats = GetMarkdownCacheStats()
+	c.Assert(stats.Hits+stats.Misses, Equals, uint64(400))
+	c.Assert(stats.Entries, Equals, 5)
+
+	SetMarkdownCacheSize(0)
+	c.Assert(getResult("{{ a|markdown }}", ctx), Equals, "<p><strong>a</strong></p>\n")
+	c.Assert(GetMarkdownCacheStats(), DeepEquals, MarkdownCacheStats{})
+}
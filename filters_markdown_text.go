@@ -0,0 +1,235 @@
+package pongo2addons
+
+import (
+	"html"
+	"strconv"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/rivo/uniseg"
+	"github.com/russross/blackfriday/v2"
+)
+
+// markdownTextDefaultWidth is the width the paragraphs of markdown_text are wrapped to, as in emails.
+const markdownTextDefaultWidth = 72
+
+// markdownText renders the document tree as plain text.
+type markdownText struct {
+	links *markdownLinks
+}
+
+// inline returns the text of the inline nodes, hard line breaks are kept as "\n".
+func (t *markdownText) inline(node *blackfriday.Node) string {
+	var b strings.Builder
+	for c := node.FirstChild; c != nil; c = c.Next {
+		switch c.Type {
+		case blackfriday.Text:
+			b.WriteString(html.UnescapeString(string(c.Literal)))
+		case blackfriday.Code:
+			b.Write(c.Literal)
+		case blackfriday.Softbreak:
+			b.WriteByte(' ')
+		case blackfriday.Hardbreak:
+			b.WriteByte('\n')
+		case blackfriday.HTMLSpan:
+			// tags are dropped, their content is in the text nodes around
+		case blackfriday.Link:
+			text := t.inline(c)
+			b.WriteString(text)
+
+			link := string(c.Destination)
+			if t.links != nil {
+				link = t.links.resolve(link)
+			}
+			// the URLs of autolinks and fragments are left out
+			if link != "" && !strings.HasPrefix(link, "#") && link != text && strings.TrimPrefix(link, "mailto:") != text {
+				b.WriteString(" [" + link + "]")
+			}
+		default:
+			// emphasis, images (their alt text) and the like
+			b.WriteString(t.inline(c))
+		}
+	}
+
+	return b.String()
+}
+
+// wrap wraps the lines of the text to the width, 0 or less keeps them.
+func (t *markdownText) wrap(text string, width int) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if width <= 0 {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, wrapLine(line, "", width, false)...)
+	}
+
+	return lines
+}
+
+// blocks returns the lines of the child blocks of the node, separated by empty lines unless tight.
+func (t *markdownText) blocks(node *blackfriday.Node, width int, tight bool) []string {
+	var lines []string
+	for c := node.FirstChild; c != nil; c = c.Next {
+		block := t.block(c, width)
+		if len(block) == 0 {
+			continue
+		}
+		if len(lines) > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+
+	return lines
+}
+
+// indentLines prefixes the lines with the marker on the first line and the indent on the others.
+func indentLines(lines []string, marker string, indent string) []string {
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = marker + line
+		case line != "":
+			lines[i] = indent + line
+		}
+	}
+
+	return lines
+}
+
+func nestedWidth(width int, indent int) int {
+	if width <= 0 {
+		return width
+	}
+
+	return max(width-indent, 1)
+}
+
+// block returns the lines of the block node.
+func (t *markdownText) block(node *blackfriday.Node, width int) []string {
+	switch node.Type {
+	case blackfriday.Paragraph:
+		return t.wrap(t.inline(node), width)
+	case blackfriday.Heading:
+		lines := t.wrap(t.inline(node), width)
+		if node.Level > 2 || len(lines) == 0 {
+			return lines
+		}
+		underline := "="
+		if node.Level == 2 {
+			underline = "-"
+		}
+		longest := 0
+		for _, line := range lines {
+			longest = max(longest, uniseg.StringWidth(line))
+		}
+		return append(lines, strings.Repeat(underline, longest))
+	case blackfriday.CodeBlock:
+		return indentLines(strings.Split(strings.TrimRight(string(node.Literal), "\n"), "\n"), "    ", "    ")
+	case blackfriday.BlockQuote:
+		lines := t.blocks(node, nestedWidth(width, 2), false)
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		return lines
+	case blackfriday.List:
+		return t.list(node, width)
+	case blackfriday.HorizontalRule:
+		return []string{"----"}
+	case blackfriday.Table:
+		var lines []string
+		node.Walk(func(row *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			if row.Type != blackfriday.TableRow || !entering {
+				return blackfriday.GoToNext
+			}
+			var cells []string
+			for cell := row.FirstChild; cell != nil; cell = cell.Next {
+				cells = append(cells, strings.Join(strings.Fields(t.inline(cell)), " "))
+			}
+			lines = append(lines, strings.Join(cells, " | "))
+			return blackfriday.SkipChildren
+		})
+		return lines
+	case blackfriday.HTMLBlock:
+		var text []rune
+		walkHTML(string(node.Literal), func(string, string, []string) {
+			text = append(text, ' ')
+		}, func(r rune, _ string) bool {
+			text = append(text, r)
+			return true
+		})
+		if strings.TrimSpace(string(text)) == "" {
+			return nil
+		}
+		return t.wrap(string(text), width)
+	}
+
+	return t.blocks(node, width, false)
+}
+
+// list returns the lines of the list items with bullets, numbers or, in definition lists,
+// the definitions indented below the terms.
+func (t *markdownText) list(node *blackfriday.Node, width int) []string {
+	tight := node.Tight
+	var lines []string
+	number := 0
+	for item := node.FirstChild; item != nil; item = item.Next {
+		marker := "- "
+		switch {
+		case node.ListFlags&blackfriday.ListTypeDefinition != 0 && item.ListFlags&blackfriday.ListTypeTerm != 0:
+			marker = ""
+		case node.ListFlags&blackfriday.ListTypeDefinition != 0:
+			marker = "    "
+		case node.ListFlags&blackfriday.ListTypeOrdered != 0:
+			number++
+			marker = strconv.Itoa(number) + ". "
+		}
+
+		indent := strings.Repeat(" ", len(marker))
+		itemLines := indentLines(t.blocks(item, nestedWidth(width, len(marker)), tight), marker, indent)
+		if len(lines) > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, itemLines...)
+	}
+
+	return lines
+}
+
+// filterMarkdownText renders the markdown text as plain text for email bodies or meta descriptions:
+// headings are underlined, lists get bullets or numbers, links are followed by their URLs in brackets
+// and paragraphs are wrapped to 72 columns. The parameter is the width, 0 doesn't wrap, followed
+// by the options of the markdown filter:
+//
+//	{{ post|markdown_text }}
+//	{{ post|markdown_text:"0,strip_frontmatter"|truncatechars:160 }}
+//	{{ post|markdown_text:"72,links=docs" }}
+func filterMarkdownText(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts, err := markdownParams(param)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:markdown_text",
+			OrigError: err,
+		}
+	}
+
+	width := markdownTextDefaultWidth
+	for _, option := range strings.Split(param.String(), ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(option)); err == nil {
+			width = n
+		}
+	}
+
+	source := in.String()
+	if opts.stripFrontMatter {
+		source = stripFrontMatter(source)
+	}
+
+	doc := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(source))
+	t := &markdownText{links: opts.links}
+
+	return pongo2.AsValue(strings.Join(t.blocks(doc, width, false), "\n")), nil
+}
@@ -0,0 +1,74 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterMarkdownText(c *C) {
+	ctx := pongo2.Context{
+		"doc": "---\ntitle: News\n---\n" +
+			"# Release *2.0*\n\n" +
+			"We are happy to announce the new release of the **template add-ons**, read the [notes](/notes \"Notes\") " +
+			"or the [FAQ](#faq) and write to <team@example.com> or https://example.com.\n\n" +
+			"## Changes\n\n" +
+			"- Faster `markdown` rendering with a cache of the rendered documents which is bounded in bytes\n" +
+			"- New filters:\n" +
+			"  1. markdown_text\n" +
+			"  2. highlight\n\n" +
+			"> Quoted  \nlines\n\n" +
+			"```go\nx := 1\n```\n\n" +
+			"| A | B |\n|---|---|\n| 1 | 2 |\n\n" +
+			"<div>Some <b>HTML</b> &amp; more</div>\n\n" +
+			"***\n\n" +
+			"![Logo](logo.png) AT&amp;T",
+	}
+
+	c.Assert(getResult(`{{ doc|markdown_text:"40,strip_frontmatter,base=https://example.com/"|safe }}`, ctx), Equals, ""+
+		"Release 2.0\n"+
+		"===========\n"+
+		"\n"+
+		"We are happy to announce the new release\n"+
+		"of the template add-ons, read the notes\n"+
+		"[https://example.com/notes] or the FAQ\n"+
+		"and write to team@example.com or\n"+
+		"https://example.com.\n"+
+		"\n"+
+		"Changes\n"+
+		"-------\n"+
+		"\n"+
+		"- Faster markdown rendering with a cache\n"+
+		"  of the rendered documents which is\n"+
+		"  bounded in bytes\n"+
+		"- New filters:\n"+
+		"  1. markdown_text\n"+
+		"  2. highlight\n"+
+		"\n"+
+		"> Quoted\n"+
+		"> lines\n"+
+		"\n"+
+		"    x := 1\n"+
+		"\n"+
+		"A | B\n"+
+		"1 | 2\n"+
+		"\n"+
+		"Some HTML & more\n"+
+		"\n"+
+		"----\n"+
+		"\n"+
+		"Logo AT&T")
+
+	// loose lists, definition lists, no wrapping
+	ctx["loose"] = "1. First item\n\n    More text\n\n2. Second item\n\nTerm\n: Definition of the term"
+	c.Assert(getResult(`{{ loose|markdown_text:0|safe }}`, ctx), Equals,
+		"1. First item\n\n   More text\n\n2. Second item\n\nTerm\n    Definition of the term")
+
+	// meta descriptions are escaped in HTML
+	ctx["intro"] = "Intro with a [link](/a) & a long paragraph of text which is longer than the line width.\n\nSecond *paragraph*."
+	c.Assert(getResult(`<meta name="description" content="{{ intro|markdown_text:0|truncatechars:30 }}">`, ctx), Equals,
+		`<meta name="description" content="Intro with a link [/a] &amp; a ...">`)
+
+	// errors
+	c.Assert(getResult(`{{ intro|markdown_text:"links=unknown" }}`, ctx), Equals, "")
+}
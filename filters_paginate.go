@@ -0,0 +1,126 @@
+package pongo2addons
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+const (
+	// paginateOnEachSide is the number of pages shown around the current one.
+	paginateOnEachSide = 2
+	// paginateOnEnds is the number of pages shown at the beginning and at the end of the range.
+	paginateOnEnds = 1
+)
+
+// Pagination is the result of the paginate filter, the paginator tag renders it.
+type Pagination struct {
+	// Total is the number of items, PageSize the number of items per page.
+	Total    int
+	PageSize int
+
+	// Page is the current page starting from 1, NumPages is never less than 1.
+	Page     int
+	NumPages int
+
+	HasPrev bool
+	HasNext bool
+	// Prev and Next are the numbers of the neighbour pages, 0 if there are none.
+	Prev int
+	Next int
+
+	// Start and End are the numbers of the first and the last item on the page starting from 1,
+	// both are 0 if there are no items.
+	Start int
+	End   int
+
+	// Pages is the page range with the far pages elided: 1 … 4 5 6 … 20.
+	Pages []PaginationPage
+}
+
+// PaginationPage is a page link or an ellipsis of the elided page range.
+type PaginationPage struct {
+	// Number is 0 for an ellipsis.
+	Number   int
+	Current  bool
+	Ellipsis bool
+}
+
+func newPagination(total, page, size, onEachSide, onEnds int) *Pagination {
+	p := &Pagination{
+		Total:    max(total, 0),
+		PageSize: size,
+		NumPages: max((max(total, 0)+size-1)/size, 1),
+	}
+
+	p.Page = min(max(page, 1), p.NumPages)
+	if p.HasPrev = p.Page > 1; p.HasPrev {
+		p.Prev = p.Page - 1
+	}
+	if p.HasNext = p.Page < p.NumPages; p.HasNext {
+		p.Next = p.Page + 1
+	}
+	if p.Total > 0 {
+		p.Start = (p.Page-1)*size + 1
+		p.End = min(p.Page*size, p.Total)
+	}
+
+	pages := func(from, to int) {
+		for n := from; n <= to; n++ {
+			p.Pages = append(p.Pages, PaginationPage{Number: n, Current: n == p.Page})
+		}
+	}
+	ellipsis := func() {
+		p.Pages = append(p.Pages, PaginationPage{Ellipsis: true})
+	}
+
+	// the same elision as Django's Paginator.get_elided_page_range
+	if p.NumPages <= (onEachSide+onEnds)*2 {
+		pages(1, p.NumPages)
+		return p
+	}
+	if p.Page > onEachSide+onEnds+2 {
+		pages(1, onEnds)
+		ellipsis()
+		pages(p.Page-onEachSide, p.Page)
+	} else {
+		pages(1, p.Page)
+	}
+	if p.Page < p.NumPages-onEachSide-onEnds-1 {
+		pages(p.Page+1, p.Page+onEachSide)
+		ellipsis()
+		pages(p.NumPages-onEnds+1, p.NumPages)
+	} else {
+		pages(p.Page+1, p.NumPages)
+	}
+
+	return p
+}
+
+// filterPaginate splits the total number of items into pages:
+//
+//	{% with pages=total|paginate:"5,20" %}{{ pages.Start }}–{{ pages.End }} of {{ pages.Total }}{% endwith %}
+//	{% paginator total|paginate:"5,20,1,1" %}
+//
+// The parameter is "page,size[,on each side[,on ends]]", the last two set how many pages
+// around the current one and at the ends of the elided range are shown (2 and 1 by default).
+func filterPaginate(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	size := pongoParam(param, 1).Integer()
+	if size < 1 {
+		return nil, &pongo2.Error{
+			Sender:    "filter:paginate",
+			OrigError: errors.New("page size is less than 1"),
+		}
+	}
+
+	onEachSide, onEnds := paginateOnEachSide, paginateOnEnds
+	if strings.TrimSpace(pongoParam(param, 2).String()) != "" {
+		onEachSide = max(pongoParam(param, 2).Integer(), 0)
+	}
+	if strings.TrimSpace(pongoParam(param, 3).String()) != "" {
+		onEnds = max(pongoParam(param, 3).Integer(), 0)
+	}
+
+	return pongo2.AsValue(newPagination(in.Integer(), pongoParam(param, 0).Integer(), size, onEachSide, onEnds)), nil
+}
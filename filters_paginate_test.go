@@ -0,0 +1,64 @@
+package pongo2addons
+
+import (
+	"fmt"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func paginationRange(p *Pagination) string {
+	out := ""
+	for i, page := range p.Pages {
+		if i > 0 {
+			out += " "
+		}
+		switch {
+		case page.Ellipsis:
+			out += "…"
+		case page.Current:
+			out += fmt.Sprintf("[%d]", page.Number)
+		default:
+			out += fmt.Sprint(page.Number)
+		}
+	}
+
+	return out
+}
+
+func (s *TestSuite1) TestNewPagination(c *C) {
+	c.Assert(paginationRange(newPagination(200, 5, 10, 1, 1)), Equals, "1 … 4 [5] 6 … 20")
+	c.Assert(paginationRange(newPagination(200, 1, 10, 2, 1)), Equals, "[1] 2 3 … 20")
+	c.Assert(paginationRange(newPagination(200, 5, 10, 2, 1)), Equals, "1 2 3 4 [5] 6 7 … 20")
+	c.Assert(paginationRange(newPagination(200, 20, 10, 2, 2)), Equals, "1 2 … 18 19 [20]")
+	c.Assert(paginationRange(newPagination(50, 3, 10, 2, 1)), Equals, "1 2 [3] 4 5")
+
+	p := newPagination(95, 10, 10, 2, 1)
+	c.Assert(p.NumPages, Equals, 10)
+	c.Assert(p.Start, Equals, 91)
+	c.Assert(p.End, Equals, 95)
+	c.Assert(p.HasPrev, Equals, true)
+	c.Assert(p.Prev, Equals, 9)
+	c.Assert(p.HasNext, Equals, false)
+	c.Assert(p.Next, Equals, 0)
+
+	// out of range pages and no items
+	c.Assert(newPagination(95, 50, 10, 2, 1).Page, Equals, 10)
+	c.Assert(newPagination(95, -1, 10, 2, 1).Page, Equals, 1)
+	p = newPagination(0, 1, 10, 2, 1)
+	c.Assert(p.NumPages, Equals, 1)
+	c.Assert(p.Start, Equals, 0)
+	c.Assert(p.End, Equals, 0)
+	c.Assert(paginationRange(p), Equals, "[1]")
+}
+
+func (s *TestSuite1) TestFilterPaginate(c *C) {
+	ctx := pongo2.Context{"total": 200}
+
+	c.Assert(getResult("{% with p=total|paginate:'5,10' %}{{ p.Start }}-{{ p.End }} of {{ p.Total }}, {{ p.Page }}/{{ p.NumPages }}{% endwith %}", ctx),
+		Equals, "41-50 of 200, 5/20")
+	c.Assert(getResult("{% with pages=total|paginate:'5,10,1,1' %}{% for p in pages.Pages %}{% if p.Ellipsis %}…{% else %}{{ p.Number }}{% endif %} {% endfor %}{% endwith %}", ctx),
+		Equals, "1 … 4 5 6 … 20 ")
+	c.Assert(getResult("{{ total|paginate:'5,0' }}", ctx), Equals, "")
+}
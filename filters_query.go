@@ -0,0 +1,825 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// queryCacheSize is the number of compiled queries kept by the query filter.
+const queryCacheSize = 256
+
+var queryCache = newLRUCache(queryCacheSize)
+
+type queryStepKind int
+
+const (
+	queryStepField queryStepKind = iota
+	queryStepIndex
+	queryStepWildcard
+	queryStepSlice
+	queryStepFilter
+	queryStepRecursive
+)
+
+type queryStep struct {
+	kind   queryStepKind
+	name   string
+	index  int
+	start  *int
+	end    *int
+	filter queryExpr
+	inner  *queryStep
+}
+
+type compiledQuery struct {
+	steps []queryStep
+	// definite queries (only fields and indexes) return a single value, all others a slice.
+	definite bool
+}
+
+// queryExpr is a node of a filter expression like "@.price > 10 && @.name".
+type queryExpr interface {
+	eval(node interface{}) (interface{}, bool)
+}
+
+type queryLiteral struct {
+	value interface{}
+}
+
+type queryPath struct {
+	steps []queryStep
+}
+
+type queryNot struct {
+	expr queryExpr
+}
+
+type queryBinary struct {
+	op          string
+	left, right queryExpr
+}
+
+// filterQuery selects values from nested maps, slices and structs using a JSONPath-like expression:
+//
+//	{{ data|query:"$.items[?(@.price > 10)].name" }}
+func filterQuery(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	q, err := compileQuery(param.String())
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:query",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(q.run(in.Interface())), nil
+}
+
+func compileQuery(src string) (*compiledQuery, error) {
+	if cached, ok := queryCache.get(src); ok {
+		return cached.(*compiledQuery), nil
+	}
+
+	p := &queryParser{src: src}
+	steps, err := p.parsePath(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpaces(); p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:])
+	}
+
+	q := &compiledQuery{steps: steps, definite: true}
+	for _, s := range steps {
+		if s.kind != queryStepField && s.kind != queryStepIndex {
+			q.definite = false
+		}
+	}
+
+	queryCache.add(src, q)
+	return q, nil
+}
+
+func (q *compiledQuery) run(data interface{}) interface{} {
+	nodes := applyQuerySteps(q.steps, []interface{}{data})
+	if q.definite {
+		if len(nodes) == 0 {
+			return nil
+		}
+		return nodes[0]
+	}
+
+	return nodes
+}
+
+func applyQuerySteps(steps []queryStep, nodes []interface{}) []interface{} {
+	for i := range steps {
+		next := make([]interface{}, 0, len(nodes))
+		for _, node := range nodes {
+			next = steps[i].apply(node, next)
+		}
+		nodes = next
+	}
+
+	return nodes
+}
+
+func (s *queryStep) apply(node interface{}, out []interface{}) []interface{} {
+	switch s.kind {
+	case queryStepField:
+		if v, ok := queryField(node, s.name); ok {
+			out = append(out, v)
+		}
+	case queryStepIndex:
+		if v, ok := queryIndex(node, s.index); ok {
+			out = append(out, v)
+		}
+	case queryStepWildcard:
+		out = append(out, queryChildren(node)...)
+	case queryStepSlice:
+		v := queryIndirect(reflect.ValueOf(node))
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			break
+		}
+		start, end := 0, v.Len()
+		if s.start != nil {
+			start = queryBound(*s.start, v.Len())
+		}
+		if s.end != nil {
+			end = queryBound(*s.end, v.Len())
+		}
+		for i := start; i < end; i++ {
+			out = append(out, v.Index(i).Interface())
+		}
+	case queryStepFilter:
+		for _, child := range queryChildren(node) {
+			if res, ok := s.filter.eval(child); ok && queryTruthy(res) {
+				out = append(out, child)
+			}
+		}
+	case queryStepRecursive:
+		for _, n := range queryDescendants(node, nil) {
+			out = s.inner.apply(n, out)
+		}
+	}
+
+	return out
+}
+
+func queryBound(i, l int) int {
+	if i < 0 {
+		i += l
+	}
+
+	return min(max(i, 0), l)
+}
+
+func queryIndirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// queryField returns a map value by key or a struct field by name or by its json tag.
+func queryField(node interface{}, name string) (interface{}, bool) {
+	v := queryIndirect(reflect.ValueOf(node))
+
+	switch v.Kind() {
+	case reflect.Map:
+		key, ok := queryMapKey(v.Type().Key(), name)
+		if !ok {
+			return nil, false
+		}
+		res := v.MapIndex(key)
+		if !res.IsValid() {
+			return nil, false
+		}
+		return res.Interface(), true
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			if f.Name == name || queryJSONName(f) == name {
+				return v.Field(i).Interface(), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// queryFieldPath follows the dotted path of fields, like "author.name", from the node.
+func queryFieldPath(node interface{}, path string) (interface{}, bool) {
+	for _, name := range strings.Split(path, ".") {
+		var ok bool
+		if node, ok = queryField(node, name); !ok {
+			return nil, false
+		}
+	}
+
+	return node, true
+}
+
+func queryMapKey(t reflect.Type, name string) (reflect.Value, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(name).Convert(t), true
+	case reflect.Interface:
+		return reflect.ValueOf(name), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(i).Convert(t), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(name, 10, 64)
+		if err != nil {
+			return reflect.Value{}, false
+		}
+		return reflect.ValueOf(i).Convert(t), true
+	}
+
+	return reflect.Value{}, false
+}
+
+func queryJSONName(f reflect.StructField) string {
+	tag := f.Tag.Get("json")
+	if idx := strings.Index(tag, ","); idx >= 0 {
+		tag = tag[:idx]
+	}
+
+	return tag
+}
+
+func queryIndex(node interface{}, idx int) (interface{}, bool) {
+	v := queryIndirect(reflect.ValueOf(node))
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, false
+	}
+
+	if idx < 0 {
+		idx += v.Len()
+	}
+	if idx < 0 || idx >= v.Len() {
+		return nil, false
+	}
+
+	return v.Index(idx).Interface(), true
+}
+
+// queryChildren returns slice items, map values sorted by key or exported struct fields.
+func queryChildren(node interface{}) []interface{} {
+	v := queryIndirect(reflect.ValueOf(node))
+
+	var out []interface{}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			out = append(out, v.Index(i).Interface())
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			out = append(out, v.MapIndex(k).Interface())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if t.Field(i).PkgPath == "" {
+				out = append(out, v.Field(i).Interface())
+			}
+		}
+	}
+
+	return out
+}
+
+// queryDescendants returns the node and everything below it. Pointers, maps and slices which
+// are their own ancestors are skipped, so cycles are cut, and the walk stops at queryMaxDepth.
+// Shared data which is not cyclic is returned once for every path to it.
+func queryDescendants(node interface{}, out []interface{}) []interface{} {
+	return queryWalk(node, 0, map[queryRef]bool{}, out)
+}
+
+// queryMaxDepth limits how deep the recursive descent goes.
+const queryMaxDepth = 64
+
+// queryRef identifies a pointer, map or slice by its address.
+type queryRef struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+func queryWalk(node interface{}, depth int, ancestors map[queryRef]bool, out []interface{}) []interface{} {
+	var refs []queryRef
+	for v := reflect.ValueOf(node); v.IsValid(); v = v.Elem() {
+		kind := v.Kind()
+		if kind == reflect.Interface {
+			continue
+		}
+		if kind != reflect.Ptr && kind != reflect.Map && kind != reflect.Slice {
+			break
+		}
+		if v.IsNil() {
+			break
+		}
+
+		ref := queryRef{typ: v.Type(), ptr: v.Pointer()}
+		if kind == reflect.Slice {
+			// empty slices may share their address
+			if v.Len() == 0 {
+				break
+			}
+			ref.len = v.Len()
+		}
+		if ancestors[ref] {
+			return out
+		}
+		refs = append(refs, ref)
+
+		if kind != reflect.Ptr {
+			break
+		}
+	}
+
+	out = append(out, node)
+	if depth >= queryMaxDepth {
+		return out
+	}
+
+	for _, ref := range refs {
+		ancestors[ref] = true
+	}
+	for _, child := range queryChildren(node) {
+		out = queryWalk(child, depth+1, ancestors, out)
+	}
+	for _, ref := range refs {
+		delete(ancestors, ref)
+	}
+
+	return out
+}
+
+func (e *queryLiteral) eval(_ interface{}) (interface{}, bool) {
+	return e.value, true
+}
+
+func (e *queryPath) eval(node interface{}) (interface{}, bool) {
+	nodes := applyQuerySteps(e.steps, []interface{}{node})
+	if len(nodes) == 0 {
+		return nil, false
+	}
+
+	return nodes[0], true
+}
+
+func (e *queryNot) eval(node interface{}) (interface{}, bool) {
+	v, ok := e.expr.eval(node)
+	return !(ok && queryTruthy(v)), true
+}
+
+func (e *queryBinary) eval(node interface{}) (interface{}, bool) {
+	l, lok := e.left.eval(node)
+
+	switch e.op {
+	case "&&":
+		if !lok || !queryTruthy(l) {
+			return false, true
+		}
+		r, rok := e.right.eval(node)
+		return rok && queryTruthy(r), true
+	case "||":
+		if lok && queryTruthy(l) {
+			return true, true
+		}
+		r, rok := e.right.eval(node)
+		return rok && queryTruthy(r), true
+	}
+
+	r, rok := e.right.eval(node)
+	if !lok || !rok {
+		return false, true
+	}
+
+	return queryCompare(e.op, l, r), true
+}
+
+func queryTruthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+
+	return v != nil
+}
+
+func queryNumber(v interface{}) (float64, bool) {
+	rv := queryIndirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
+
+func queryCompare(op string, l, r interface{}) bool {
+	var cmp int
+
+	if lf, ok := queryNumber(l); ok {
+		rf, ok := queryNumber(r)
+		if !ok {
+			return op == "!="
+		}
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	} else {
+		lv, rv := queryIndirect(reflect.ValueOf(l)), queryIndirect(reflect.ValueOf(r))
+		if lv.Kind() == reflect.String && rv.Kind() == reflect.String {
+			cmp = strings.Compare(lv.String(), rv.String())
+		} else {
+			eq := lv.IsValid() == rv.IsValid() && (!lv.IsValid() || reflect.DeepEqual(lv.Interface(), rv.Interface()))
+			switch op {
+			case "==":
+				return eq
+			case "!=":
+				return !eq
+			}
+			return false
+		}
+	}
+
+	switch op {
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	}
+
+	return false
+}
+
+type queryParser struct {
+	src string
+	pos int
+}
+
+func (p *queryParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("query %q, position %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *queryParser) skipSpaces() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *queryParser) peek(s string) bool {
+	return strings.HasPrefix(p.src[p.pos:], s)
+}
+
+// parsePath parses a chain of steps. The root query starts with an optional "$",
+// a relative path inside a filter starts with "@".
+func (p *queryParser) parsePath(root bool) ([]queryStep, error) {
+	p.skipSpaces()
+
+	var steps []queryStep
+	switch {
+	case root && p.peek("$"):
+		p.pos++
+	case root && p.pos < len(p.src) && isQueryNameRune(rune(p.src[p.pos])):
+		// "items[0]" is accepted as a shortcut for "$.items[0]"
+		steps = append(steps, queryStep{kind: queryStepField, name: p.parseName()})
+	}
+
+	for p.pos < len(p.src) {
+		switch {
+		case p.peek(".."):
+			p.pos += 2
+			inner, err := p.parseDotOrBracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, queryStep{kind: queryStepRecursive, inner: &inner})
+		case p.peek("."), p.peek("["):
+			s, err := p.parseDotOrBracket()
+			if err != nil {
+				return nil, err
+			}
+			steps = append(steps, s)
+		default:
+			return steps, nil
+		}
+	}
+
+	return steps, nil
+}
+
+func (p *queryParser) parseDotOrBracket() (queryStep, error) {
+	if p.peek("[") {
+		return p.parseBracket()
+	}
+	if p.peek(".") {
+		p.pos++
+	}
+
+	if p.peek("*") {
+		p.pos++
+		return queryStep{kind: queryStepWildcard}, nil
+	}
+
+	name := p.parseName()
+	if name == "" {
+		return queryStep{}, p.errorf("field name expected")
+	}
+
+	return queryStep{kind: queryStepField, name: name}, nil
+}
+
+func isQueryNameRune(r rune) bool {
+	return r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (p *queryParser) parseName() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		r := rune(p.src[p.pos])
+		if r < 0x80 && !isQueryNameRune(r) {
+			break
+		}
+		p.pos++
+	}
+
+	return p.src[start:p.pos]
+}
+
+func (p *queryParser) parseBracket() (queryStep, error) {
+	p.pos++ // consume "["
+	p.skipSpaces()
+
+	var step queryStep
+	switch {
+	case p.peek("*"):
+		p.pos++
+		step = queryStep{kind: queryStepWildcard}
+	case p.peek("'"), p.peek(`"`):
+		name, err := p.parseString()
+		if err != nil {
+			return queryStep{}, err
+		}
+		step = queryStep{kind: queryStepField, name: name}
+	case p.peek("?"):
+		p.pos++
+		p.skipSpaces()
+		if !p.peek("(") {
+			return queryStep{}, p.errorf(`"(" expected after "?"`)
+		}
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return queryStep{}, err
+		}
+		if p.skipSpaces(); !p.peek(")") {
+			return queryStep{}, p.errorf(`")" expected`)
+		}
+		p.pos++
+		step = queryStep{kind: queryStepFilter, filter: expr}
+	default:
+		var err error
+		if step, err = p.parseIndexOrSlice(); err != nil {
+			return queryStep{}, err
+		}
+	}
+
+	if p.skipSpaces(); !p.peek("]") {
+		return queryStep{}, p.errorf(`"]" expected`)
+	}
+	p.pos++
+
+	return step, nil
+}
+
+func (p *queryParser) parseIndexOrSlice() (queryStep, error) {
+	start, err := p.parseOptionalInt()
+	if err != nil {
+		return queryStep{}, err
+	}
+
+	if p.skipSpaces(); !p.peek(":") {
+		if start == nil {
+			return queryStep{}, p.errorf("index expected")
+		}
+		return queryStep{kind: queryStepIndex, index: *start}, nil
+	}
+	p.pos++ // consume ":"
+
+	end, err := p.parseOptionalInt()
+	if err != nil {
+		return queryStep{}, err
+	}
+
+	return queryStep{kind: queryStepSlice, start: start, end: end}, nil
+}
+
+func (p *queryParser) parseOptionalInt() (*int, error) {
+	p.skipSpaces()
+	start := p.pos
+	if p.peek("-") {
+		p.pos++
+	}
+	for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(p.src[start:p.pos])
+	if err != nil {
+		return nil, p.errorf("bad index %q", p.src[start:p.pos])
+	}
+
+	return &i, nil
+}
+
+func (p *queryParser) parseString() (string, error) {
+	quote := p.src[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		switch {
+		case c == '\\' && p.pos+1 < len(p.src):
+			b.WriteByte(p.src[p.pos+1])
+			p.pos += 2
+		case c == quote:
+			p.pos++
+			return b.String(), nil
+		default:
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpaces(); p.peek("||"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryBinary{op: "||", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpaces(); p.peek("&&"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &queryBinary{op: "&&", left: left, right: right}
+	}
+
+	return left, nil
+}
+
+var queryCompareOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+func (p *queryParser) parseUnary() (queryExpr, error) {
+	p.skipSpaces()
+
+	switch {
+	case p.peek("!") && !p.peek("!="):
+		p.pos++
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &queryNot{expr: expr}, nil
+	case p.peek("("):
+		p.pos++
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipSpaces(); !p.peek(")") {
+			return nil, p.errorf(`")" expected`)
+		}
+		p.pos++
+		return expr, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	for _, op := range queryCompareOps {
+		if p.peek(op) {
+			p.pos += len(op)
+			right, err := p.parseOperand()
+			if err != nil {
+				return nil, err
+			}
+			return &queryBinary{op: op, left: left, right: right}, nil
+		}
+	}
+
+	return left, nil
+}
+
+func (p *queryParser) parseOperand() (queryExpr, error) {
+	p.skipSpaces()
+
+	switch {
+	case p.peek("@"):
+		p.pos++
+		steps, err := p.parsePath(false)
+		if err != nil {
+			return nil, err
+		}
+		return &queryPath{steps: steps}, nil
+	case p.peek("'"), p.peek(`"`):
+		s, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &queryLiteral{value: s}, nil
+	case p.peek("true"):
+		p.pos += 4
+		return &queryLiteral{value: true}, nil
+	case p.peek("false"):
+		p.pos += 5
+		return &queryLiteral{value: false}, nil
+	case p.peek("null"):
+		p.pos += 4
+		return &queryLiteral{value: nil}, nil
+	}
+
+	start := p.pos
+	for p.pos < len(p.src) && strings.IndexByte("+-.0123456789eE", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, p.errorf("operand expected")
+	}
+
+	f, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+	if err != nil {
+		return nil, p.errorf("bad number %q", p.src[start:p.pos])
+	}
+
+	return &queryLiteral{value: f}, nil
+}
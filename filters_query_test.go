@@ -0,0 +1,90 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+type queryItem struct {
+	Name  string  `json:"name"`
+	Price float64 `json:"price"`
+	Tags  []string
+}
+
+type queryNode struct {
+	Name     string       `json:"name"`
+	Next     *queryNode   `json:"next"`
+	Children []*queryNode `json:"children"`
+}
+
+func (s *TestSuite1) TestFilterQuery(c *C) {
+	data := map[string]interface{}{
+		"store-name": "Corner shop",
+		"items": []interface{}{
+			map[string]interface{}{"name": "apple", "price": 5},
+			map[string]interface{}{"name": "melon", "price": 12.5},
+			map[string]interface{}{"name": "cherry", "price": 30},
+		},
+		"owner": &queryItem{Name: "Bob", Price: 1, Tags: []string{"a", "b", "c"}},
+	}
+	ctx := pongo2.Context{"data": data}
+
+	c.Assert(getResult(`{{ data|query:"$['store-name']" }}`, ctx), Equals, "Corner shop")
+	c.Assert(getResult(`{{ data|query:"$.store-name" }}`, ctx), Equals, "Corner shop")
+	c.Assert(getResult(`{{ data|query:"$.items[0].name" }}`, ctx), Equals, "apple")
+	c.Assert(getResult(`{{ data|query:"items[-1].name" }}`, ctx), Equals, "cherry")
+	c.Assert(getResult(`{{ data|query:"$.items[5].name" }}`, ctx), Equals, "")
+	c.Assert(getResult(`{{ data|query:"$.owner.name" }}`, ctx), Equals, "Bob")
+	c.Assert(getResult(`{{ data|query:"$.owner.Tags[1]" }}`, ctx), Equals, "b")
+
+	c.Assert(getResult(`{% for n in data|query:"$.items[?(@.price>10)].name" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "melon;cherry;")
+	c.Assert(getResult(`{% for n in data|query:"$.items[?(@.price > 10 && @.name != 'melon')].name" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "cherry;")
+	c.Assert(getResult(`{% for n in data|query:"$.items[?(@.name == 'apple' || @.price >= 30)].name" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "apple;cherry;")
+	c.Assert(getResult(`{% for n in data|query:"$.items[*].name" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "apple;melon;cherry;")
+	c.Assert(getResult(`{% for n in data|query:"$.items[1:].price" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "12.500000;30;")
+	c.Assert(getResult(`{% for n in data|query:"$..name" %}{{ n }};{% endfor %}`, ctx),
+		Equals, "apple;melon;cherry;Bob;")
+	c.Assert(getResult(`{{ data|query:"$.items[?(@.price > 100)]"|length }}`, ctx), Equals, "0")
+
+	// cycles are cut, shared nodes are kept on every path
+	root := &queryNode{Name: "root"}
+	child := &queryNode{Name: "child", Next: root}
+	root.Next = root
+	root.Children = []*queryNode{child, child}
+	loop := map[string]interface{}{"name": "map"}
+	loop["self"] = loop
+	cyclic := pongo2.Context{"root": root, "loop": loop}
+	c.Assert(getResult(`{% for n in root|query:"$..name" %}{{ n }};{% endfor %}`, cyclic), Equals, "root;child;child;")
+	c.Assert(getResult(`{% for n in loop|query:"$..name" %}{{ n }};{% endfor %}`, cyclic), Equals, "map;")
+
+	author := &queryItem{Name: "Ann"}
+	posts := pongo2.Context{"posts": []map[string]interface{}{{"Author": author}, {"Author": author}}}
+	c.Assert(getResult(`{% for n in posts|query:"$..name" %}{{ n }};{% endfor %}`, posts), Equals, "Ann;Ann;")
+	c.Assert(getResult(`{% for n in posts|query:"$[*].Author.name" %}{{ n }};{% endfor %}`, posts), Equals, "Ann;Ann;")
+
+	// the descent stops at queryMaxDepth
+	chain := &queryNode{Name: "0"}
+	for i := 0; i < 2*queryMaxDepth; i++ {
+		chain = &queryNode{Name: "n", Next: chain}
+	}
+	c.Assert(getResult(`{{ chain|query:"$..name"|length }}`, pongo2.Context{"chain": chain}), Equals, "65")
+
+	// errors
+	_, err := pongo2.RenderTemplateString(`{{ data|query:"$.items[" }}`, ctx)
+	c.Assert(err, NotNil)
+	_, err = pongo2.RenderTemplateString(`{{ data|query:"$.items[?(@.price >)]" }}`, ctx)
+	c.Assert(err, NotNil)
+
+	// compiled queries are cached
+	q1, err := compileQuery("$.items[0]")
+	c.Assert(err, IsNil)
+	q2, err := compileQuery("$.items[0]")
+	c.Assert(err, IsNil)
+	c.Assert(q1 == q2, Equals, true)
+}
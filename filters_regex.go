@@ -0,0 +1,178 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+const (
+	// regexCacheSize is the number of compiled patterns kept by the regex filters.
+	regexCacheSize = 128
+	// regexDefaultMaxPatternLength is the longest pattern the regex filters compile by default.
+	regexDefaultMaxPatternLength = 256
+)
+
+var (
+	regexCache = newLRUCache(regexCacheSize)
+
+	regexMaxPatternLength int64 = regexDefaultMaxPatternLength
+)
+
+// SetRegexMaxPatternLength limits the length in bytes of the patterns the regex filters compile,
+// longer patterns are errors. The patterns are RE2 (package regexp), matching is linear in the
+// input size, the limit bounds the compile cost. 0 or less means no limit.
+func SetRegexMaxPatternLength(n int) {
+	atomic.StoreInt64(&regexMaxPatternLength, int64(n))
+}
+
+// compileRegex returns the compiled pattern from the cache or compiles and caches it.
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if limit := atomic.LoadInt64(&regexMaxPatternLength); limit > 0 && int64(len(pattern)) > limit {
+		return nil, fmt.Errorf("pattern is longer than %d bytes", limit)
+	}
+
+	if re, ok := regexCache.get(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexCache.add(pattern, re)
+
+	return re, nil
+}
+
+// regexParams splits the parameter at the commas, "\," is a literal comma. A slice parameter
+// is taken as is, this way patterns and replacements may contain anything.
+func regexParams(param *pongo2.Value) []string {
+	if param.CanSlice() && !param.IsString() {
+		out := make([]string, param.Len())
+		for i := range out {
+			out[i] = param.Index(i).String()
+		}
+		return out
+	}
+
+	var out []string
+	var b strings.Builder
+	s := param.String()
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == ',':
+			b.WriteByte(',')
+			i++
+		case s[i] == ',':
+			out = append(out, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return append(out, b.String())
+}
+
+// regexFilterParams compiles the pattern of the parameters and parses the optional count at position n.
+func regexFilterParams(param *pongo2.Value, sender string, n int) (*regexp.Regexp, []string, int, *pongo2.Error) {
+	params := regexParams(param)
+	re, err := compileRegex(params[0])
+	if err != nil {
+		return nil, nil, 0, &pongo2.Error{
+			Sender:    "filter:" + sender,
+			OrigError: err,
+		}
+	}
+
+	count := -1
+	if len(params) > n && strings.TrimSpace(params[n]) != "" {
+		if count, err = strconv.Atoi(strings.TrimSpace(params[n])); err != nil {
+			return nil, nil, 0, &pongo2.Error{
+				Sender:    "filter:" + sender,
+				OrigError: fmt.Errorf("count %q is not a number", params[n]),
+			}
+		}
+	}
+
+	return re, params, count, nil
+}
+
+// filterRegexReplace replaces the matches of the pattern, the replacement may refer to the groups as $1 or ${name}:
+//
+//	{{ phone|regex_replace:"\\D," }}
+//	{{ date|regex_replace:"(\\d+)-(\\d+)-(\\d+),$3.$2.$1" }}
+//
+// Backslashes are doubled in template strings.
+func filterRegexReplace(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	re, params, _, err := regexFilterParams(param, "regex_replace", 2)
+	if err != nil {
+		return nil, err
+	}
+
+	repl := ""
+	if len(params) > 1 {
+		repl = params[1]
+	}
+
+	return pongo2.AsValue(re.ReplaceAllString(in.String(), repl)), nil
+}
+
+// filterRegexMatch reports whether the text contains a match of the pattern: {% if email|regex_match:"^[^@]+@[^@]+$" %}.
+func filterRegexMatch(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	re, _, _, err := regexFilterParams(param, "regex_match", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return pongo2.AsValue(re.MatchString(in.String())), nil
+}
+
+// filterRegexFindAll returns the matches of the pattern, at most the count if given: {{ text|regex_find_all:"#\\w+,5" }}.
+// With a single group the group values are returned, with more groups a list of the groups for every match.
+func filterRegexFindAll(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	re, _, count, err := regexFilterParams(param, "regex_find_all", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := re.FindAllStringSubmatch(in.String(), count)
+	switch re.NumSubexp() {
+	case 0:
+		out := make([]string, len(matches))
+		for i, m := range matches {
+			out[i] = m[0]
+		}
+		return pongo2.AsValue(out), nil
+	case 1:
+		out := make([]string, len(matches))
+		for i, m := range matches {
+			out[i] = m[1]
+		}
+		return pongo2.AsValue(out), nil
+	}
+
+	out := make([][]string, len(matches))
+	for i, m := range matches {
+		out[i] = m[1:]
+	}
+
+	return pongo2.AsValue(out), nil
+}
+
+// filterRegexSplit splits the text at the matches of the pattern into at most count parts if given:
+//
+//	{% for part in tags|regex_split:"\\s*[\\,;]\\s*" %}
+func filterRegexSplit(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	re, _, count, err := regexFilterParams(param, "regex_split", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return pongo2.AsValue(re.Split(in.String(), count)), nil
+}
@@ -0,0 +1,64 @@
+package pongo2addons
+
+import (
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterRegexReplace(c *C) {
+	ctx := pongo2.Context{"phone": "+1 (555) 123-4567", "date": "2024-03-15"}
+
+	c.Assert(getResult(`{{ phone|regex_replace:"\\D," }}`, ctx), Equals, "15551234567")
+	c.Assert(getResult(`{{ phone|regex_replace:"\\D" }}`, ctx), Equals, "15551234567")
+	c.Assert(getResult(`{{ date|regex_replace:"(\\d+)-(\\d+)-(\\d+),$3.$2.$1" }}`, ctx), Equals, "15.03.2024")
+	c.Assert(getResult(`{{ date|regex_replace:"(?P<y>\\d+)-\\d+-\\d+,${y}" }}`, ctx), Equals, "2024")
+	c.Assert(getResult(`{{ date|regex_replace:"-,\\, " }}`, ctx), Equals, "2024, 03, 15")
+	c.Assert(getResult(`{{ date|regex_replace:"\\d{2\\,},#" }}`, ctx), Equals, "#-#-#")
+
+	// a slice parameter is taken as is
+	ctx["params"] = []string{"-", ", "}
+	c.Assert(getResult(`{{ date|regex_replace:params }}`, ctx), Equals, "2024, 03, 15")
+
+	// errors
+	c.Assert(getResult(`{{ date|regex_replace:"(," }}`, ctx), Equals, "")
+}
+
+func (s *TestSuite1) TestFilterRegexMatchFindSplit(c *C) {
+	ctx := pongo2.Context{"text": "Tags: #go #pongo2 and #templates", "kv": "a=1; b=2; c=3"}
+
+	c.Assert(getResult(`{% if text|regex_match:"#\\w+" %}yes{% endif %}`, ctx), Equals, "yes")
+	c.Assert(getResult(`{% if text|regex_match:"^#" %}yes{% else %}no{% endif %}`, ctx), Equals, "no")
+
+	c.Assert(getResult(`{{ text|regex_find_all:"#\\w+"|join:" " }}`, ctx), Equals, "#go #pongo2 #templates")
+	c.Assert(getResult(`{{ text|regex_find_all:"#\\w+,2"|join:" " }}`, ctx), Equals, "#go #pongo2")
+	c.Assert(getResult(`{{ text|regex_find_all:"#(\\w+)"|join:" " }}`, ctx), Equals, "go pongo2 templates")
+	c.Assert(getResult(`{% for m in kv|regex_find_all:"(\\w)=(\\d)" %}{{ m.0 }}:{{ m.1 }} {% endfor %}`, ctx), Equals, "a:1 b:2 c:3 ")
+	c.Assert(getResult(`{{ text|regex_find_all:"#\\w+,x" }}`, ctx), Equals, "")
+
+	c.Assert(getResult(`{{ kv|regex_split:";\\s*"|join:"|" }}`, ctx), Equals, "a=1|b=2|c=3")
+	c.Assert(getResult(`{{ kv|regex_split:";\\s*,2"|join:"|" }}`, ctx), Equals, "a=1|b=2; c=3")
+}
+
+func (s *TestSuite1) TestRegexPatternLimitAndCache(c *C) {
+	defer SetRegexMaxPatternLength(regexDefaultMaxPatternLength)
+
+	long := strings.Repeat("a", regexDefaultMaxPatternLength+1)
+	_, err := compileRegex(long)
+	c.Assert(err, ErrorMatches, "pattern is longer than 256 bytes")
+
+	SetRegexMaxPatternLength(0)
+	_, err = compileRegex(long)
+	c.Assert(err, IsNil)
+
+	SetRegexMaxPatternLength(3)
+	c.Assert(getResult(`{{ "abc"|regex_match:"abcd" }}`, nil), Equals, "")
+
+	re1, err := compileRegex("x+")
+	c.Assert(err, IsNil)
+	re2, err := compileRegex("x+")
+	c.Assert(err, IsNil)
+	c.Assert(re1 == re2, Equals, true)
+}
@@ -0,0 +1,756 @@
+package pongo2addons
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// serializeOptions are shared by the json, to_yaml, to_toml, to_xml and urlencode_map filters.
+// They come as a filter parameter "indent,order[,root]", e.g. {{ data|to_yaml:"4,sorted" }}.
+type serializeOptions struct {
+	indent int
+	// sorted orders struct fields by name as well, map keys are always sorted.
+	sorted bool
+	// root is the name of the XML root element.
+	root string
+}
+
+// serialPair and serialMap keep an ordered representation of maps and structs,
+// so every format renders keys in the same deterministic order.
+type serialPair struct {
+	key   string
+	value interface{}
+}
+
+type serialMap []serialPair
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+func serializeParams(param *pongo2.Value, defaultIndent int) serializeOptions {
+	opts := serializeOptions{
+		indent: defaultIndent,
+		sorted: strings.TrimSpace(pongoParam(param, 1).String()) == "sorted",
+		root:   strings.TrimSpace(pongoParam(param, 2).String()),
+	}
+
+	if strings.TrimSpace(pongoParam(param, 0).String()) != "" {
+		opts.indent = max(pongoParam(param, 0).Integer(), 0)
+	}
+	if opts.root == "" {
+		opts.root = "root"
+	}
+
+	return opts
+}
+
+// serialNormalize converts the input into nil, bool, int64, uint64, float64, string, time.Time,
+// []interface{} and serialMap values. Struct fields are named by the format tag ("yaml", "toml", "xml"),
+// then by the "json" tag, then by the field name. For "json" the values implementing json.Marshaler
+// are kept as json.RawMessage and byte slices as they are, so they are encoded like encoding/json does.
+// Cyclic data is an error.
+func serialNormalize(v reflect.Value, tag string, sorted bool) (interface{}, error) {
+	n := &serialNormalizer{tag: tag, sorted: sorted, path: map[serialRef]bool{}}
+	return n.normalize(v)
+}
+
+// serialRef identifies a pointer, map or slice by its address.
+type serialRef struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+type serialNormalizer struct {
+	tag    string
+	sorted bool
+	// path keeps the pointers, maps and slices from the root to the current value.
+	path map[serialRef]bool
+}
+
+// enter adds the pointer, map or slice to the path, it fails if it is there already.
+func (n *serialNormalizer) enter(v reflect.Value) (func(), error) {
+	ref := serialRef{typ: v.Type(), ptr: v.Pointer()}
+	if v.Kind() == reflect.Slice {
+		ref.len = v.Len()
+	}
+	if n.path[ref] {
+		return nil, fmt.Errorf("cyclic value of type %s", v.Type())
+	}
+	n.path[ref] = true
+
+	return func() { delete(n.path, ref) }, nil
+}
+
+func (n *serialNormalizer) normalize(v reflect.Value) (interface{}, error) {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return nil, nil
+		}
+		if n.tag == "json" && v.Type().Implements(jsonMarshalerType) {
+			return n.marshalJSON(v)
+		}
+		if v.Kind() == reflect.Ptr {
+			leave, err := n.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if n.tag == "json" && v.Type().Implements(jsonMarshalerType) {
+		return n.marshalJSON(v)
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t, nil
+	}
+	if v.Type().Implements(textMarshalerType) {
+		text, err := v.Interface().(encoding.TextMarshaler).MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			if n.tag == "json" {
+				return v.Bytes(), nil
+			}
+			return string(v.Bytes()), nil
+		}
+		if v.Kind() == reflect.Slice && v.Len() > 0 {
+			leave, err := n.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			item, err := n.normalize(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	case reflect.Map:
+		if !v.IsNil() {
+			leave, err := n.enter(v)
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		out := make(serialMap, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			item, err := n.normalize(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, serialPair{key: fmt.Sprint(iter.Key().Interface()), value: item})
+		}
+		sort.SliceStable(out, func(i, j int) bool { return out[i].key < out[j].key })
+		return out, nil
+	case reflect.Struct:
+		out, err := n.structFields(v, nil)
+		if err != nil {
+			return nil, err
+		}
+		if n.sorted {
+			sort.SliceStable(out, func(i, j int) bool { return out[i].key < out[j].key })
+		}
+		return out, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func (n *serialNormalizer) marshalJSON(v reflect.Value) (interface{}, error) {
+	js, err := v.Interface().(json.Marshaler).MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.RawMessage(js), nil
+}
+
+func (n *serialNormalizer) structFields(v reflect.Value, out serialMap) (serialMap, error) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+
+		name, opts := serialFieldName(f, n.tag)
+		if name == "-" {
+			continue
+		}
+
+		// embedded structs without a name are inlined like encoding/json does
+		if f.Anonymous && name == "" {
+			fv := v.Field(i)
+			for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				leave, err := n.enter(fv)
+				if err != nil {
+					return nil, err
+				}
+				defer leave()
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				var err error
+				if out, err = n.structFields(fv, out); err != nil {
+					return nil, err
+				}
+				continue
+			}
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if strings.Contains(opts, "omitempty") && v.Field(i).IsZero() {
+			continue
+		}
+
+		item, err := n.normalize(v.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, serialPair{key: name, value: item})
+	}
+
+	return out, nil
+}
+
+func serialFieldName(f reflect.StructField, tag string) (string, string) {
+	value, ok := f.Tag.Lookup(tag)
+	if !ok {
+		value = f.Tag.Get("json")
+	}
+
+	name, opts, _ := strings.Cut(value, ",")
+	return name, opts
+}
+
+// serialString formats a normalized scalar value.
+func serialString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case time.Time:
+		return t.Format(time.RFC3339Nano)
+	}
+
+	return fmt.Sprint(v)
+}
+
+func (m serialMap) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("{")
+	for i, p := range m {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		key, err := json.Marshal(p.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(p.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}")
+
+	return b.Bytes(), nil
+}
+
+func filterJSON(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := serializeParams(param, 0)
+
+	var data interface{} = in.Interface()
+	if opts.sorted {
+		var err error
+		if data, err = serialNormalize(reflect.ValueOf(data), "json", true); err != nil {
+			return nil, &pongo2.Error{
+				Sender:    "filter:json",
+				OrigError: err,
+			}
+		}
+	}
+
+	var js []byte
+	var err error
+	if opts.indent > 0 {
+		js, err = json.MarshalIndent(data, "", strings.Repeat(" ", opts.indent))
+	} else {
+		js, err = json.Marshal(data)
+	}
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:json",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(string(js)), nil
+}
+
+func filterToYAML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := serializeParams(param, 2)
+
+	data, err := serialNormalize(reflect.ValueOf(in.Interface()), "yaml", opts.sorted)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_yaml",
+			OrigError: err,
+		}
+	}
+
+	node, err := yamlNode(data)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_yaml",
+			OrigError: err,
+		}
+	}
+
+	var b bytes.Buffer
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(max(opts.indent, 1))
+	if err := enc.Encode(node); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_yaml",
+			OrigError: err,
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_yaml",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(b.String()), nil
+}
+
+func yamlNode(v interface{}) (*yaml.Node, error) {
+	switch t := v.(type) {
+	case serialMap:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, p := range t {
+			key, err := yamlNode(p.key)
+			if err != nil {
+				return nil, err
+			}
+			value, err := yamlNode(p.value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, key, value)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, item := range t {
+			value, err := yamlNode(item)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, value)
+		}
+		return node, nil
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return node, nil
+}
+
+func filterToTOML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := serializeParams(param, 0)
+
+	data, err := serialNormalize(reflect.ValueOf(in.Interface()), "toml", opts.sorted)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_toml",
+			OrigError: err,
+		}
+	}
+
+	table, ok := data.(serialMap)
+	if !ok {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_toml",
+			OrigError: fmt.Errorf("top-level value must be a map or a struct, got %T", in.Interface()),
+		}
+	}
+
+	var b bytes.Buffer
+	w := &tomlWriter{b: &b, indent: strings.Repeat(" ", opts.indent)}
+	if err := w.writeTable(nil, table, 0); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_toml",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(b.String()), nil
+}
+
+type tomlWriter struct {
+	b      *bytes.Buffer
+	indent string
+}
+
+func isTOMLTable(v interface{}) bool {
+	_, ok := v.(serialMap)
+	return ok
+}
+
+func isTOMLTableArray(v interface{}) bool {
+	items, ok := v.([]interface{})
+	if !ok || len(items) == 0 {
+		return false
+	}
+	for _, item := range items {
+		if !isTOMLTable(item) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// writeTable writes plain keys first, then sub-tables and arrays of tables, as TOML requires.
+func (w *tomlWriter) writeTable(path []string, table serialMap, depth int) error {
+	prefix := strings.Repeat(w.indent, depth)
+
+	for _, p := range table {
+		if p.value == nil || isTOMLTable(p.value) || isTOMLTableArray(p.value) {
+			continue
+		}
+		value, err := tomlValue(p.value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w.b, "%s%s = %s\n", prefix, tomlKey(p.key), value)
+	}
+
+	for _, p := range table {
+		sub := append(append([]string{}, path...), p.key)
+
+		switch {
+		case isTOMLTable(p.value):
+			w.writeHeader("[", sub, depth)
+			if err := w.writeTable(sub, p.value.(serialMap), depth+1); err != nil {
+				return err
+			}
+		case isTOMLTableArray(p.value):
+			for _, item := range p.value.([]interface{}) {
+				w.writeHeader("[[", sub, depth)
+				if err := w.writeTable(sub, item.(serialMap), depth+1); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (w *tomlWriter) writeHeader(open string, path []string, depth int) {
+	if w.b.Len() > 0 {
+		w.b.WriteString("\n")
+	}
+
+	keys := make([]string, len(path))
+	for i := range path {
+		keys[i] = tomlKey(path[i])
+	}
+
+	closing := strings.Repeat("]", len(open))
+	fmt.Fprintf(w.b, "%s%s%s%s\n", strings.Repeat(w.indent, depth), open, strings.Join(keys, "."), closing)
+}
+
+func tomlKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return tomlString(key)
+		}
+	}
+
+	return key
+}
+
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+func tomlValue(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return tomlString(t), nil
+	case float64:
+		switch {
+		case math.IsNaN(t):
+			return "nan", nil
+		case math.IsInf(t, 1):
+			return "inf", nil
+		case math.IsInf(t, -1):
+			return "-inf", nil
+		}
+		s := strconv.FormatFloat(t, 'f', -1, 64)
+		if !strings.ContainsAny(s, ".eE") {
+			s += ".0"
+		}
+		return s, nil
+	case time.Time:
+		return t.Format(time.RFC3339Nano), nil
+	case []interface{}:
+		items := make([]string, 0, len(t))
+		for _, item := range t {
+			if item == nil {
+				continue
+			}
+			value, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case serialMap:
+		items := make([]string, 0, len(t))
+		for _, p := range t {
+			if p.value == nil {
+				continue
+			}
+			value, err := tomlValue(p.value)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(p.key)+" = "+value)
+		}
+		if len(items) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	case bool, int64, uint64:
+		return fmt.Sprint(t), nil
+	}
+
+	return "", fmt.Errorf("unsupported TOML value %T", v)
+}
+
+func filterToXML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := serializeParams(param, 0)
+
+	data, err := serialNormalize(reflect.ValueOf(in.Interface()), "xml", opts.sorted)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_xml",
+			OrigError: err,
+		}
+	}
+
+	// a top-level list is wrapped into the root element
+	if items, ok := data.([]interface{}); ok {
+		data = serialMap{{key: "item", value: items}}
+	}
+
+	var b bytes.Buffer
+	enc := xml.NewEncoder(&b)
+	if opts.indent > 0 {
+		enc.Indent("", strings.Repeat(" ", opts.indent))
+	}
+	if err := xmlEncodeValue(enc, xmlName(opts.root), data); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_xml",
+			OrigError: err,
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:to_xml",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(b.String()), nil
+}
+
+// xmlEncodeValue writes maps as nested elements and lists as repeated elements with the same name.
+func xmlEncodeValue(enc *xml.Encoder, name string, v interface{}) error {
+	if items, ok := v.([]interface{}); ok {
+		for _, item := range items {
+			if err := xmlEncodeValue(enc, name, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	if m, ok := v.(serialMap); ok {
+		for _, p := range m {
+			if err := xmlEncodeValue(enc, xmlName(p.key), p.value); err != nil {
+				return err
+			}
+		}
+	} else if v != nil {
+		if err := enc.EncodeToken(xml.CharData(serialString(v))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(start.End())
+}
+
+// xmlName replaces the characters that are not allowed in XML element names.
+func xmlName(key string) string {
+	var b strings.Builder
+	for i, r := range key {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+			b.WriteRune(r)
+		case i > 0 && (r == '-' || r == '.' || unicode.IsDigit(r)):
+			b.WriteRune(r)
+		case i == 0 && unicode.IsDigit(r):
+			b.WriteRune('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+
+	return b.String()
+}
+
+func filterURLEncodeMap(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := serializeParams(param, 0)
+
+	data, err := serialNormalize(reflect.ValueOf(in.Interface()), "url", opts.sorted)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:urlencode_map",
+			OrigError: err,
+		}
+	}
+
+	m, ok := data.(serialMap)
+	if !ok {
+		return nil, &pongo2.Error{
+			Sender:    "filter:urlencode_map",
+			OrigError: fmt.Errorf("value must be a map or a struct, got %T", in.Interface()),
+		}
+	}
+
+	var pairs []string
+	for _, p := range m {
+		pairs = urlencodePairs(pairs, p.key, p.value)
+	}
+
+	return pongo2.AsValue(strings.Join(pairs, "&")), nil
+}
+
+// urlencodePairs adds lists as repeated keys and nested maps as "key[sub]=value".
+func urlencodePairs(pairs []string, key string, v interface{}) []string {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, item := range t {
+			pairs = urlencodePairs(pairs, key, item)
+		}
+		return pairs
+	case serialMap:
+		for _, p := range t {
+			pairs = urlencodePairs(pairs, key+"["+p.key+"]", p.value)
+		}
+		return pairs
+	}
+
+	return append(pairs, url.QueryEscape(key)+"="+url.QueryEscape(serialString(v)))
+}
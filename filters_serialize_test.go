@@ -0,0 +1,154 @@
+package pongo2addons
+
+import (
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+type serialServer struct {
+	Host    string            `json:"host"`
+	Port    int               `json:"port" yaml:"listen_port"`
+	Debug   bool              `json:"debug,omitempty"`
+	Ratio   float64           `json:"ratio"`
+	Labels  map[string]string `json:"labels"`
+	Backups []serialBackup    `json:"backups"`
+	secret  string
+}
+
+type serialBackup struct {
+	Name string `json:"name"`
+	At   time.Time
+}
+
+type serialNode struct {
+	Name string      `json:"name"`
+	Next *serialNode `json:"next,omitempty"`
+}
+
+type serialCustom struct{}
+
+func (serialCustom) MarshalJSON() ([]byte, error) { return []byte(`"custom"`), nil }
+
+func serialTestData() serialServer {
+	return serialServer{
+		Host:   "example.com",
+		Port:   8080,
+		Ratio:  1,
+		Labels: map[string]string{"z": "last", "a": "first", "with space": "x"},
+		Backups: []serialBackup{
+			{Name: "daily", At: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		},
+		secret: "hidden",
+	}
+}
+
+func (s *TestSuite1) TestFilterJSONOptions(c *C) {
+	T := map[string]interface{}{"b": 1, "a": []int{1, 2}}
+	c.Assert(getResult("{{ tojs|json:'2'|safe }}", pongo2.Context{"tojs": T}), Equals, "{\n  \"a\": [\n    1,\n    2\n  ],\n  \"b\": 1\n}")
+
+	D := struct {
+		Z string `json:"z"`
+		A string `json:"a"`
+	}{Z: "z", A: "a"}
+	c.Assert(getResult("{{ tojs|json|safe }}", pongo2.Context{"tojs": D}), Equals, `{"z":"z","a":"a"}`)
+	c.Assert(getResult("{{ tojs|json:',sorted'|safe }}", pongo2.Context{"tojs": D}), Equals, `{"a":"a","z":"z"}`)
+
+	// the values are encoded the same with or without sorting
+	M := map[string]interface{}{"bytes": []byte("hi"), "custom": serialCustom{}, "ptr": &serialCustom{}}
+	c.Assert(getResult("{{ tojs|json|safe }}", pongo2.Context{"tojs": M}), Equals, `{"bytes":"aGk=","custom":"custom","ptr":"custom"}`)
+	c.Assert(getResult("{{ tojs|json:',sorted'|safe }}", pongo2.Context{"tojs": M}), Equals, `{"bytes":"aGk=","custom":"custom","ptr":"custom"}`)
+}
+
+func (s *TestSuite1) TestFilterSerializeCycles(c *C) {
+	loop := &serialNode{Name: "loop"}
+	loop.Next = loop
+	m := map[string]interface{}{"a": 1}
+	m["self"] = m
+	ctx := pongo2.Context{"loop": loop, "map": m}
+
+	for _, filter := range []string{"json:',sorted'", "to_yaml", "to_toml", "to_xml", "urlencode_map"} {
+		_, err := pongo2.RenderTemplateString("{{ loop|"+filter+" }}", ctx)
+		c.Assert(err, ErrorMatches, ".*cyclic value of type \\*pongo2addons.serialNode.*", Commentf(filter))
+		_, err = pongo2.RenderTemplateString("{{ map|"+filter+" }}", ctx)
+		c.Assert(err, ErrorMatches, ".*cyclic value of type map\\[string\\]interface \\{\\}.*", Commentf(filter))
+	}
+
+	// shared values are not cycles
+	shared := &serialNode{Name: "shared"}
+	c.Assert(getResult("{{ data|json:',sorted'|safe }}", pongo2.Context{"data": []*serialNode{shared, shared}}), Equals,
+		`[{"name":"shared"},{"name":"shared"}]`)
+}
+
+func (s *TestSuite1) TestFilterToYAML(c *C) {
+	ctx := pongo2.Context{"data": serialTestData()}
+
+	c.Assert(getResult("{{ data|to_yaml|safe }}", ctx), Equals, `host: example.com
+listen_port: 8080
+ratio: 1
+labels:
+  a: first
+  with space: x
+  z: last
+backups:
+  - name: daily
+    At: 2020-01-02T03:04:05Z
+`)
+
+	c.Assert(getResult("{{ data|to_yaml:'4,sorted'|safe }}", pongo2.Context{"data": serialBackup{Name: "yes"}}), Equals,
+		"At: 0001-01-01T00:00:00Z\nname: \"yes\"\n")
+	c.Assert(getResult("{{ data|to_yaml|safe }}", pongo2.Context{"data": []int{1, 2}}), Equals, "- 1\n- 2\n")
+}
+
+func (s *TestSuite1) TestFilterToTOML(c *C) {
+	ctx := pongo2.Context{"data": serialTestData()}
+
+	c.Assert(getResult("{{ data|to_toml|safe }}", ctx), Equals, `host = "example.com"
+port = 8080
+ratio = 1.0
+
+[labels]
+a = "first"
+"with space" = "x"
+z = "last"
+
+[[backups]]
+name = "daily"
+At = 2020-01-02T03:04:05Z
+`)
+
+	nested := map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": "x\n\"y\""}}}
+	c.Assert(getResult("{{ data|to_toml:'2'|safe }}", pongo2.Context{"data": nested}), Equals,
+		"[a]\n\n  [a.b]\n    c = \"x\\n\\\"y\\\"\"\n")
+
+	_, err := pongo2.RenderTemplateString("{{ data|to_toml }}", pongo2.Context{"data": []int{1}})
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite1) TestFilterToXML(c *C) {
+	ctx := pongo2.Context{"data": serialTestData()}
+
+	c.Assert(getResult("{{ data|to_xml|safe }}", ctx), Equals,
+		`<root><host>example.com</host><port>8080</port><ratio>1</ratio>`+
+			`<labels><a>first</a><with_space>x</with_space><z>last</z></labels>`+
+			`<backups><name>daily</name><At>2020-01-02T03:04:05Z</At></backups></root>`)
+
+	c.Assert(getResult("{{ data|to_xml:'2,,list'|safe }}", pongo2.Context{"data": []string{"a<b", "c"}}), Equals,
+		"<list>\n  <item>a&lt;b</item>\n  <item>c</item>\n</list>")
+}
+
+func (s *TestSuite1) TestFilterURLEncodeMap(c *C) {
+	data := map[string]interface{}{
+		"b":      2,
+		"a":      "x y&z",
+		"tags":   []string{"go", "web"},
+		"filter": map[string]int{"max": 10},
+	}
+	c.Assert(getResult("{{ data|urlencode_map|safe }}", pongo2.Context{"data": data}), Equals,
+		"a=x+y%26z&b=2&filter%5Bmax%5D=10&tags=go&tags=web")
+
+	_, err := pongo2.RenderTemplateString("{{ data|urlencode_map }}", pongo2.Context{"data": "a"})
+	c.Assert(err, NotNil)
+}
@@ -0,0 +1,373 @@
+package pongo2addons
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/extemporalgenome/slug"
+	"github.com/flosch/pongo2/v6"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	slugTransliterationsMu sync.RWMutex
+
+	// slugTransliterations map lowercase letters to their Latin spelling, keyed by language.
+	slugTransliterations = map[string]map[rune]string{}
+)
+
+func init() {
+	RegisterSlugTransliteration("de", map[rune]string{'ä': "ae", 'ö': "oe", 'ü': "ue", 'ß': "ss"})
+	RegisterSlugTransliteration("ru", map[rune]string{
+		'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo", 'ж': "zh", 'з': "z", 'и': "i",
+		'й': "y", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t",
+		'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "",
+		'э': "e", 'ю': "yu", 'я': "ya",
+	})
+}
+
+// RegisterSlugTransliteration adds the spelling of lowercase letters for the "lang" option of
+// the slugify filter. Uppercase letters are capitalized spellings of their lowercase forms.
+func RegisterSlugTransliteration(lang string, table map[rune]string) {
+	slugTransliterationsMu.Lock()
+	defer slugTransliterationsMu.Unlock()
+
+	lang = strings.ToLower(lang)
+	if slugTransliterations[lang] == nil {
+		slugTransliterations[lang] = map[rune]string{}
+	}
+	for r, s := range table {
+		slugTransliterations[lang][unicode.ToLower(r)] = s
+	}
+}
+
+// slugOptions are parsed from the slugify parameter.
+type slugOptions struct {
+	separator string
+	lower     bool
+	// maxLength is the maximal length in runes, the slug is cut on a word boundary. 0 means no limit.
+	maxLength int
+	lang      string
+	// unicode keeps the letters of non-Latin scripts as they are, without decomposition.
+	unicode bool
+}
+
+// slugParams parses "sep=_,max=50,lang=de,keepcase,unicode". An integer parameter is the max length.
+func slugParams(param *pongo2.Value) slugOptions {
+	opts := slugOptions{separator: "-", lower: true}
+
+	if param.IsNumber() {
+		opts.maxLength = param.Integer()
+		return opts
+	}
+
+	for _, option := range strings.Split(param.String(), ",") {
+		key, value := strings.TrimSpace(option), ""
+		if i := strings.Index(key, "="); i >= 0 {
+			key, value = strings.TrimSpace(key[:i]), strings.TrimSpace(key[i+1:])
+		}
+
+		switch key {
+		case "sep":
+			opts.separator = value
+		case "max":
+			opts.maxLength, _ = strconv.Atoi(value)
+		case "lang":
+			opts.lang = strings.ToLower(value)
+		case "keepcase":
+			opts.lower = false
+		case "unicode":
+			opts.unicode = true
+		}
+	}
+
+	return opts
+}
+
+// transliterate replaces the letters found in the language table.
+func transliterate(s string, lang string) string {
+	slugTransliterationsMu.RLock()
+	defer slugTransliterationsMu.RUnlock()
+
+	table := slugTransliterations[lang]
+	if len(table) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	for _, r := range norm.NFC.String(s) {
+		lower := unicode.ToLower(r)
+		spelling, ok := table[lower]
+		switch {
+		case !ok:
+			b.WriteRune(r)
+		case lower != r && spelling != "":
+			runes := []rune(spelling)
+			b.WriteRune(unicode.ToUpper(runes[0]))
+			b.WriteString(string(runes[1:]))
+		default:
+			b.WriteString(spelling)
+		}
+	}
+
+	return b.String()
+}
+
+func isNonLatinLetter(r rune) bool {
+	return unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r)
+}
+
+// slugWords splits the text into the words of the slug like slug.Slug does: letters lose their
+// diacritical marks, everything but letters and numbers separates words. With preserve the
+// letters of non-Latin scripts keep their marks.
+func slugWords(s string, preserve bool) []string {
+	var words []string
+	var word []rune
+
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	keep := func(r rune) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			word = append(word, r)
+		case unicode.IsMark(r) || unicode.Is(unicode.Sk, r):
+			// skip
+		default:
+			flush()
+		}
+	}
+
+	nonLatin := false
+	for _, r := range norm.NFC.String(s) {
+		switch {
+		case preserve && isNonLatinLetter(r):
+			word = append(word, r)
+			nonLatin = true
+		case preserve && nonLatin && unicode.IsMark(r):
+			word = append(word, r)
+		default:
+			nonLatin = false
+			for _, d := range norm.NFKD.String(string(r)) {
+				keep(d)
+			}
+		}
+	}
+	flush()
+
+	return words
+}
+
+// slugify makes the slug of the text with the options.
+func slugify(s string, opts slugOptions) string {
+	return joinSlugWords(slugifyWords(s, opts), opts.separator, opts.maxLength)
+}
+
+func slugifyWords(s string, opts slugOptions) []string {
+	s = transliterate(s, opts.lang)
+	if opts.lower {
+		s = strings.ToLower(s)
+	}
+
+	return slugWords(s, opts.unicode)
+}
+
+// joinSlugWords joins the words up to maxLength runes, 0 means no limit.
+func joinSlugWords(words []string, separator string, maxLength int) string {
+	if maxLength <= 0 {
+		return strings.Join(words, separator)
+	}
+
+	var out []rune
+	sep := []rune(separator)
+	for _, w := range words {
+		word := []rune(w)
+		if len(out) == 0 {
+			if len(word) > maxLength {
+				// a single word longer than the limit is cut anyway
+				return string(word[:maxLength])
+			}
+			out = word
+			continue
+		}
+		if len(out)+len(sep)+len(word) > maxLength {
+			break
+		}
+		out = append(append(out, sep...), word...)
+	}
+
+	return string(out)
+}
+
+// Slugify makes the slug of the text like the slugify filter does with the same options:
+//
+//	pongo2addons.Slugify("Ärger über Straße", "lang=de,max=50")
+func Slugify(text string, options string) string {
+	if options == "" {
+		return slug.Slug(text)
+	}
+
+	return slugify(text, slugParams(pongo2.AsValue(options)))
+}
+
+// filterSlugify creates the slug of the text. Without parameter it is slug.Slug, otherwise the options are:
+//
+//	{{ title|slugify:"sep=_" }}                separator, "-" by default
+//	{{ title|slugify:"max=50" }}               maximal length cut on a word boundary, also {{ title|slugify:50 }}
+//	{{ title|slugify:"keepcase" }}             don't lowercase
+//	{{ title|slugify:"lang=de" }}              transliteration table, "de" (ä→ae) and "ru" are built in
+//	{{ title|slugify:"unicode" }}              keep non-Latin scripts like Cyrillic or CJK intact
+func filterSlugify(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	if param.IsNumber() {
+		return pongo2.AsValue(slugify(in.String(), slugParams(param))), nil
+	}
+
+	return pongo2.AsValue(Slugify(in.String(), param.String())), nil
+}
+
+// SlugStore keeps the slugs in use for unique_slug and UniqueSlug.
+type SlugStore interface {
+	// Reserve marks the slug as used. It returns false if the slug is taken already,
+	// checking and marking must be atomic to avoid duplicates.
+	Reserve(slug string) (bool, error)
+}
+
+// MemorySlugStore is an in-memory SlugStore safe for concurrent use.
+type MemorySlugStore struct {
+	mu    sync.Mutex
+	slugs map[string]bool
+}
+
+// NewMemorySlugStore returns a store with the given slugs taken.
+func NewMemorySlugStore(slugs ...string) *MemorySlugStore {
+	s := &MemorySlugStore{slugs: make(map[string]bool, len(slugs))}
+	for _, taken := range slugs {
+		s.slugs[taken] = true
+	}
+
+	return s
+}
+
+// Reserve marks the slug as used, it returns false if it is taken already.
+func (s *MemorySlugStore) Reserve(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.slugs[name] {
+		return false, nil
+	}
+	s.slugs[name] = true
+
+	return true, nil
+}
+
+// Has reports whether the slug is taken.
+func (s *MemorySlugStore) Has(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.slugs[name]
+}
+
+var (
+	slugStoresMu sync.RWMutex
+
+	// slugStores are the stores unique_slug finds by name.
+	slugStores = map[string]SlugStore{}
+)
+
+// RegisterSlugStore makes the store available to the unique_slug filter by name.
+func RegisterSlugStore(name string, store SlugStore) {
+	slugStoresMu.Lock()
+	defer slugStoresMu.Unlock()
+
+	slugStores[name] = store
+}
+
+// uniqueSlugMaxAttempts limits the slugs UniqueSlug tries before it gives up.
+const uniqueSlugMaxAttempts = 1000
+
+// UniqueSlug makes the slug of the text with the slugify options and reserves it in the store.
+// Taken slugs get the suffixes "-2", "-3" and so on (with the configured separator), which fit
+// into the maximal length. It fails after uniqueSlugMaxAttempts taken slugs.
+func UniqueSlug(store SlugStore, text string, options string) (string, error) {
+	opts := slugParams(pongo2.AsValue(options))
+	words := slugifyWords(text, opts)
+	if options == "" {
+		words = []string{slug.Slug(text)}
+	}
+	if len(words) == 0 || words[0] == "" {
+		return "", errors.New("text has no letters or digits for a slug")
+	}
+
+	separator := opts.separator
+	if separator == "" {
+		separator = "-"
+	}
+
+	for n := 1; n <= uniqueSlugMaxAttempts; n++ {
+		candidate := joinSlugWords(words, opts.separator, opts.maxLength)
+		if n > 1 {
+			suffix := separator + strconv.Itoa(n)
+			if opts.maxLength > 0 && opts.maxLength <= len([]rune(suffix)) {
+				return "", fmt.Errorf("max length %d leaves no room for the suffix %q", opts.maxLength, suffix)
+			}
+			candidate = joinSlugWords(words, opts.separator, max(opts.maxLength-len([]rune(suffix)), 0)) + suffix
+		}
+
+		ok, err := store.Reserve(candidate)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("no free slug for %q after %d attempts", joinSlugWords(words, opts.separator, opts.maxLength), uniqueSlugMaxAttempts)
+}
+
+// filterUniqueSlug makes a slug which is not in the store yet and reserves it. The parameter is
+// a SlugStore, or the name of a store registered with RegisterSlugStore followed by the slugify options:
+//
+//	{{ title|unique_slug:store }}
+//	{{ title|unique_slug:"posts,lang=de,max=50" }}
+func filterUniqueSlug(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	store, ok := param.Interface().(SlugStore)
+	options := ""
+	if !ok {
+		parts := strings.SplitN(param.String(), ",", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) > 1 {
+			options = parts[1]
+		}
+
+		slugStoresMu.RLock()
+		store, ok = slugStores[name]
+		slugStoresMu.RUnlock()
+
+		if !ok {
+			return nil, &pongo2.Error{
+				Sender:    "filter:unique_slug",
+				OrigError: fmt.Errorf("slug store %q is not registered", name),
+			}
+		}
+	}
+
+	out, err := UniqueSlug(store, in.String(), options)
+	if err != nil {
+		return nil, &pongo2.Error{
+			Sender:    "filter:unique_slug",
+			OrigError: err,
+		}
+	}
+
+	return pongo2.AsValue(out), nil
+}
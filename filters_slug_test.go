@@ -0,0 +1,103 @@
+package pongo2addons
+
+import (
+	"errors"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterSlugifyOptions(c *C) {
+	ctx := pongo2.Context{
+		"title": "Ärger über Straße – the Große Test!",
+		"ru":    "Привет, мир: съешь ещё щей",
+		"cjk":   "東京 タワー café",
+	}
+
+	// the default is slug.Slug
+	c.Assert(getResult("{{ title|slugify }}", ctx), Equals, "arger-uber-straße-the-große-test")
+
+	c.Assert(getResult("{{ title|slugify:'sep=_' }}", ctx), Equals, "arger_uber_straße_the_große_test")
+	c.Assert(getResult("{{ title|slugify:'sep=' }}", ctx), Equals, "argeruberstraßethegroßetest")
+	c.Assert(getResult("{{ title|slugify:'keepcase' }}", ctx), Equals, "Arger-uber-Straße-the-Große-Test")
+	c.Assert(getResult("{{ title|slugify:'lang=de' }}", ctx), Equals, "aerger-ueber-strasse-the-grosse-test")
+	c.Assert(getResult("{{ title|slugify:'lang=de,keepcase,sep=.' }}", ctx), Equals, "Aerger.ueber.Strasse.the.Grosse.Test")
+
+	// max length is cut on a word boundary
+	c.Assert(getResult("{{ title|slugify:'lang=de,max=20' }}", ctx), Equals, "aerger-ueber-strasse")
+	c.Assert(getResult("{{ title|slugify:'lang=de,max=19' }}", ctx), Equals, "aerger-ueber")
+	c.Assert(getResult("{{ title|slugify:4 }}", ctx), Equals, "arge")
+
+	// Cyrillic: slug.Slug decomposes "й" and "ё", unicode keeps them, lang=ru transliterates
+	c.Assert(getResult("{{ ru|slugify }}", ctx), Equals, "привет-мир-съешь-еще-щеи")
+	c.Assert(getResult("{{ ru|slugify:'unicode' }}", ctx), Equals, "привет-мир-съешь-ещё-щей")
+	c.Assert(getResult("{{ ru|slugify:'lang=ru' }}", ctx), Equals, "privet-mir-sesh-eshchyo-shchey")
+	c.Assert(getResult("{{ ru|slugify:'lang=ru,keepcase' }}", ctx), Equals, "Privet-mir-sesh-eshchyo-shchey")
+
+	// CJK is kept, Latin letters lose their accents
+	c.Assert(getResult("{{ cjk|slugify:'unicode' }}", ctx), Equals, "東京-タワー-cafe")
+	c.Assert(getResult("{{ cjk|slugify:'unicode,max=6' }}", ctx), Equals, "東京-タワー")
+
+	// custom tables
+	RegisterSlugTransliteration("x-test", map[rune]string{'Ø': "oe", 'å': "aa"})
+	c.Assert(getResult("{{ 'Ørsted på Åland'|slugify:'lang=x-test' }}", ctx), Equals, "oersted-paa-aaland")
+}
+
+type failingSlugStore struct{}
+
+func (failingSlugStore) Reserve(string) (bool, error) { return false, errors.New("store is down") }
+
+type fullSlugStore struct{}
+
+func (fullSlugStore) Reserve(string) (bool, error) { return false, nil }
+
+func (s *TestSuite1) TestUniqueSlug(c *C) {
+	store := NewMemorySlugStore("hello-world", "hello-world-2")
+
+	out, err := UniqueSlug(store, "Hello World", "")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello-world-3")
+	c.Assert(store.Has("hello-world-3"), Equals, true)
+
+	out, err = UniqueSlug(store, "Hello World", "")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello-world-4")
+
+	out, err = UniqueSlug(store, "Hello World", "sep=_")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello_world")
+	out, err = UniqueSlug(store, "Hello World", "sep=_")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello_world_2")
+
+	// the suffix fits into the max length
+	out, err = UniqueSlug(store, "Hello World", "max=11")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello-2")
+	out, err = UniqueSlug(store, "Hello World", "max=12")
+	c.Assert(err, IsNil)
+	c.Assert(out, Equals, "hello-3")
+
+	// errors
+	_, err = UniqueSlug(store, "!!!", "")
+	c.Assert(err, NotNil)
+	_, err = UniqueSlug(NewMemorySlugStore("ab"), "ab", "max=2")
+	c.Assert(err, NotNil)
+	_, err = UniqueSlug(failingSlugStore{}, "ab", "")
+	c.Assert(err, ErrorMatches, "store is down")
+	_, err = UniqueSlug(fullSlugStore{}, "Hello World", "")
+	c.Assert(err, ErrorMatches, `no free slug for "hello-world" after 1000 attempts`)
+}
+
+func (s *TestSuite1) TestFilterUniqueSlug(c *C) {
+	RegisterSlugStore("test-posts", NewMemorySlugStore("aerger"))
+	ctx := pongo2.Context{"store": NewMemorySlugStore("post"), "title": "Ärger"}
+
+	c.Assert(getResult("{{ 'Post'|unique_slug:store }} {{ 'Post'|unique_slug:store }}", ctx), Equals, "post-2 post-3")
+	c.Assert(getResult("{{ title|unique_slug:'test-posts,lang=de' }} {{ title|unique_slug:'test-posts,lang=de' }}", ctx), Equals,
+		"aerger-2 aerger-3")
+	c.Assert(getResult("{{ title|unique_slug:'test-posts' }}", ctx), Equals, "arger")
+
+	c.Assert(getResult("{{ title|unique_slug:'unknown' }}", ctx), Equals, "")
+}
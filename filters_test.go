@@ -136,8 +136,37 @@ func (s *TestSuite1) TestFilterPrintError(c *C) {
 
 	err3 := 10
 	c.Assert(getResult("<h1>{{ err|printerror }}</h1>", pongo2.Context{"err": err3}), Equals, "<h1>10</h1>")
+
+	// nil and typed nil errors print the default text
+	var typedNil *testPrintError
+	var nilErr error = typedNil
+	c.Assert(getResult("<h1>{{ err|printerror }}</h1>", pongo2.Context{"err": nil}), Equals, "<h1></h1>")
+	c.Assert(getResult("<h1>{{ err|printerror:'no error' }}</h1>", pongo2.Context{"err": nil}), Equals, "<h1>no error</h1>")
+	c.Assert(getResult("<h1>{{ err|printerror:'no error' }}</h1>", pongo2.Context{"err": nilErr}), Equals, "<h1>no error</h1>")
+	c.Assert(getResult("<h1>{{ err|printerror:'no error' }}</h1>", pongo2.Context{"err": &testPrintError{"set"}}), Equals, "<h1>set</h1>")
+
+	// other nil values are not errors and print nothing
+	var nilSlice []string
+	c.Assert(getResult("<h1>{{ x|printerror }}</h1>", pongo2.Context{"x": nilSlice}), Equals, "<h1></h1>")
+	c.Assert(getResult("<h1>{{ x|printerror:'no error' }}</h1>", pongo2.Context{"x": nilSlice}), Equals, "<h1></h1>")
+	c.Assert(getResult("<h1>{{ x|printerror:'no error' }}</h1>", pongo2.Context{"x": map[string]int(nil)}), Equals, "<h1></h1>")
+
+	// a panicking Error() is an error of the template
+	_, perr := pongo2.RenderTemplateString("{{ err|printerror }}", pongo2.Context{"err": testPanicError{}})
+	c.Assert(perr, NotNil)
+	c.Assert(perr.Error(), Matches, ".*testPanicError.Error\\(\\) panicked: boom.*")
+}
+
+type testPrintError struct {
+	msg string
 }
 
+func (e *testPrintError) Error() string { return e.msg }
+
+type testPanicError struct{}
+
+func (e testPanicError) Error() string { panic("boom") }
+
 func (s *TestSuite1) TestFilterSolidLineBreaksBR(c *C) {
 	text := "simpleerror"
 	c.Assert(getResult("{{ text|solidlinebreaksbr: '6'|safe }}", pongo2.Context{"text": text}), Equals, "simple<br />error")
@@ -151,6 +180,28 @@ func (s *TestSuite1) TestFilterSolidLineBreaksBR(c *C) {
 	c.Assert(getResult("{{ text|solidlinebreaksbr: '6'|safe }}", pongo2.Context{"text": text}), Equals, "one 🐘 <br />and th<br />ree 🐋")
 
 	c.Assert(getResult("{{ 'one 🐘 and three 🐋'|solidlinebreaksbr: '6,<s>'|safe }}", pongo2.Context{"text": text}), Equals, "one 🐘 <s>and th<s>ree 🐋")
+
+	// grapheme clusters are never split
+	ctx := pongo2.Context{"text": "👩‍👩‍👧🇩🇪ééab"}
+	c.Assert(getResult("{{ text|solidlinebreaksbr: '2' }}", ctx), Equals, "👩‍👩‍👧🇩🇪<br />éé<br />ab")
+
+	// with the html option tags are kept and not counted, the text is escaped
+	ctx = pongo2.Context{"text": "<b>abc</b>d&amp;e < f"}
+	c.Assert(getResult("{{ text|solidlinebreaksbr: '3,,html' }}", ctx), Equals, "<b>abc</b><br />d&amp;e<br /> &lt; <br />f")
+	ctx["safe"] = pongo2.AsSafeValue("<b>abc</b>d&amp;e < f")
+	c.Assert(getResult("{{ safe|solidlinebreaksbr: '3' }}", ctx), Equals, "<b>abc</b><br />d&amp;e<br /> &lt; <br />f")
+
+	// otherwise the markup is escaped as text
+	ctx = pongo2.Context{"text": "<img src=x onerror=alert(1)>hi<script>alert(2)</script>"}
+	c.Assert(getResult("{{ text|solidlinebreaksbr: 20 }}", ctx), Equals,
+		"&lt;img src=x onerror=a<br />lert(1)&gt;hi&lt;script&gt;al<br />ert(2)&lt;/script&gt;")
+	c.Assert(getResult("{{ text|solidlinebreaksbr: '20,<wbr>,words' }}", ctx), Equals,
+		"&lt;img src=x onerror=alert(1)&gt;hi&lt;script&gt;alert(2)&lt;/script&gt;")
+
+	// only the long words are broken
+	ctx = pongo2.Context{"text": "a short and averyverylongword"}
+	c.Assert(getResult("{{ text|solidlinebreaksbr: '5,<wbr>,words' }}", ctx), Equals, "a short and avery<wbr>veryl<wbr>ongwo<wbr>rd")
+	c.Assert(getResult("{{ text|solidlinebreaksbr: 5 }}", ctx), Equals, "a sho<br />rt an<br />d ave<br />ryver<br />ylong<br />word")
 }
 
 func (s *TestSuite1) TestFilterRange0(c *C) {
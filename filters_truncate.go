@@ -0,0 +1,283 @@
+package pongo2addons
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/flosch/pongo2/v6"
+	"golang.org/x/net/html"
+)
+
+// truncateHTMLOptions are parsed from the "length[,ellipsis[,words][,visible]]" parameter
+// of truncatechars_html_ext and truncatewords_html_ext.
+type truncateHTMLOptions struct {
+	length   int
+	ellipsis string
+	// words moves the cut back to the end of the last whole word.
+	words bool
+	// visible counts a run of whitespace as a single character, like a browser renders it.
+	visible bool
+}
+
+func truncateHTMLParams(param *pongo2.Value) truncateHTMLOptions {
+	opts := truncateHTMLOptions{ellipsis: "..."}
+
+	if param.IsNumber() {
+		opts.length = param.Integer()
+		return opts
+	}
+
+	parts := strings.Split(param.String(), ",")
+	opts.length = pongo2.AsValue(strings.TrimSpace(parts[0])).Integer()
+	if len(parts) > 1 {
+		opts.ellipsis = parts[1]
+	}
+	for _, flag := range parts[min(len(parts), 2):] {
+		switch strings.TrimSpace(flag) {
+		case "words":
+			opts.words = true
+		case "visible":
+			opts.visible = true
+		}
+	}
+
+	return opts
+}
+
+// htmlVoidElements never have a closing tag.
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "keygen": true, "link": true, "meta": true, "param": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// htmlRawTextElements keep their content as markup, it is neither counted nor truncated.
+var htmlRawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true,
+}
+
+// walkHTML tokenizes the HTML value. Every visible character is passed to text decoded and
+// with its source, which is a single rune or a whole character reference like "&amp;".
+// Tags, comments, doctypes and the content of raw text elements go to markup along with
+// the lowercased tag name (empty for all but tags) and the stack of open elements. Walking stops as soon as text returns false.
+func walkHTML(value string, markup func(raw string, tag string, stack []string), text func(r rune, raw string) bool) {
+	var stack []string
+
+	z := html.NewTokenizer(strings.NewReader(value))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			return
+		}
+		raw := string(z.Raw())
+		tag := ""
+
+		switch tt {
+		case html.TextToken:
+			if len(stack) > 0 && htmlRawTextElements[stack[len(stack)-1]] {
+				markup(raw, "", stack)
+				continue
+			}
+			for i := 0; i < len(raw); {
+				r, size := utf8.DecodeRuneInString(raw[i:])
+				if r == '&' {
+					if n, decoded := htmlEntityAt(raw[i:]); n > 0 {
+						r, size = decoded, n
+					}
+				}
+				if !text(r, raw[i:i+size]) {
+					return
+				}
+				i += size
+			}
+			continue
+		case html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			tag = string(name)
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			if tag = string(name); !htmlVoidElements[tag] {
+				stack = append(stack, tag)
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag = string(name)
+			// in malformed HTML the closed element is not always on top,
+			// the elements opened after it are implicitly closed with it
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i] == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+		}
+
+		markup(raw, tag, stack)
+	}
+}
+
+// htmlEntityAt returns the length and the first decoded rune of the character reference
+// at the beginning of s, or zero if there is none.
+func htmlEntityAt(s string) (int, rune) {
+	end := 1
+	if end < len(s) && s[end] == '#' {
+		end++
+		if end < len(s) && (s[end] == 'x' || s[end] == 'X') {
+			end++
+		}
+	}
+	for end < len(s) && (s[end] >= '0' && s[end] <= '9' || s[end] >= 'a' && s[end] <= 'z' || s[end] >= 'A' && s[end] <= 'Z') {
+		end++
+	}
+	if end < len(s) && s[end] == ';' {
+		end++
+	}
+
+	// legacy references may come without ";" and be followed by letters, like "&copy2023",
+	// shorten the candidate until it is decoded as a whole
+	for ; end > 1; end-- {
+		decoded := html.UnescapeString(s[:end])
+		if decoded != s[:end] && !strings.HasSuffix(decoded, s[end-1:end]) {
+			r, _ := utf8.DecodeRuneInString(decoded)
+			return end, r
+		}
+	}
+
+	return 0, 0
+}
+
+// htmlTextRunes returns the decoded visible text of the HTML value.
+func htmlTextRunes(value string) []rune {
+	var text []rune
+	walkHTML(value, func(string, string, []string) {}, func(r rune, _ string) bool {
+		text = append(text, r)
+		return true
+	})
+
+	return text
+}
+
+// truncateHTMLAt writes the HTML value up to its first n visible characters,
+// adds the ellipsis and closes all elements left open.
+func truncateHTMLAt(value string, n int, ellipsis string) string {
+	if n <= 0 {
+		return ellipsis
+	}
+
+	var out strings.Builder
+	var open []string
+
+	written := 0
+	walkHTML(value, func(raw string, _ string, stack []string) {
+		out.WriteString(raw)
+		open = stack
+	}, func(_ rune, raw string) bool {
+		out.WriteString(raw)
+		written++
+		return written < n
+	})
+
+	out.WriteString(ellipsis)
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+
+	return out.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// filterTruncatecharsHTML extends the pongo2 builtin truncatechars_html: {{ text|truncatechars_html_ext:"100,…,words,visible" }}.
+// The ellipsis is counted against the length like the builtin does.
+func filterTruncatecharsHTML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := truncateHTMLParams(param)
+	if opts.length <= 0 {
+		return pongo2.AsSafeValue(""), nil
+	}
+
+	value := in.String()
+	text := htmlTextRunes(value)
+	limit := max(opts.length-utf8.RuneCountInString(opts.ellipsis), 0)
+
+	// find the number of text runes to keep
+	cut, counted := 0, 0
+	prevSpace := true
+	for cut < len(text) {
+		space := unicode.IsSpace(text[cut])
+		if !opts.visible || !space || !prevSpace {
+			if counted >= limit {
+				break
+			}
+			counted++
+		}
+		prevSpace = space
+		cut++
+	}
+
+	truncated := false
+	for _, r := range text[cut:] {
+		if !opts.visible || !unicode.IsSpace(r) {
+			truncated = true
+			break
+		}
+	}
+	if !truncated {
+		return pongo2.AsSafeValue(value), nil
+	}
+
+	if opts.words && cut > 0 && cut < len(text) && isWordRune(text[cut-1]) && isWordRune(text[cut]) {
+		i := cut
+		for i > 0 && !unicode.IsSpace(text[i-1]) {
+			i--
+		}
+		// a single word longer than the limit is cut anyway
+		if i > 0 {
+			cut = i
+		}
+	}
+	if opts.words {
+		for cut > 0 && unicode.IsSpace(text[cut-1]) {
+			cut--
+		}
+	}
+
+	return pongo2.AsSafeValue(truncateHTMLAt(value, cut, opts.ellipsis)), nil
+}
+
+// filterTruncatewordsHTML extends the pongo2 builtin truncatewords_html: {{ text|truncatewords_html_ext:"20, (more)" }}.
+// Words are separated by whitespace, the ellipsis is added only if some words were removed.
+func filterTruncatewordsHTML(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	opts := truncateHTMLParams(param)
+	if opts.length <= 0 {
+		return pongo2.AsSafeValue(""), nil
+	}
+
+	value := in.String()
+	text := htmlTextRunes(value)
+
+	cut, words := 0, 0
+	inWord := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			inWord = false
+			continue
+		}
+		if !inWord {
+			if words == opts.length {
+				break
+			}
+			words++
+			inWord = true
+		}
+		cut = i + 1
+	}
+
+	if strings.TrimSpace(string(text[cut:])) == "" {
+		return pongo2.AsSafeValue(value), nil
+	}
+
+	return pongo2.AsSafeValue(truncateHTMLAt(value, cut, opts.ellipsis)), nil
+}
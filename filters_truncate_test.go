@@ -0,0 +1,79 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterTruncatecharsHTML(c *C) {
+	ctx := pongo2.Context{"text": `<p>This is a <b>long</b> text</p>`}
+
+	// same defaults as the pongo2 builtin
+	c.Assert(getResult("{{ text|truncatechars_html_ext:10 }}", ctx), Equals, `<p>This is...</p>`)
+	c.Assert(getResult("{{ text|truncatechars_html_ext:100 }}", ctx), Equals, `<p>This is a <b>long</b> text</p>`)
+	c.Assert(getResult("{{ text|truncatechars_html_ext:0 }}", ctx), Equals, ``)
+
+	// custom ellipsis
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'12,…' }}", ctx), Equals, `<p>This is a <b>l…</b></p>`)
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'12,' }}", ctx), Equals, `<p>This is a <b>lo</b></p>`)
+
+	// whole words
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'12,…,words' }}", ctx), Equals, `<p>This is a…</p>`)
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'5,…,words' }}", pongo2.Context{"text": "Supercalifragilistic"}), Equals, `Supe…`)
+
+	// visible characters only
+	spaced := pongo2.Context{"text": "<p>one     two\n\n   three four</p>"}
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'13,…' }}", spaced), Equals, "<p>one     two\n…</p>")
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'13,…,visible' }}", spaced), Equals, "<p>one     two\n\n   thre…</p>")
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'13,…,visible,words' }}", spaced), Equals, "<p>one     two…</p>")
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'19,…,visible' }}", spaced), Equals, "<p>one     two\n\n   three four</p>")
+}
+
+func (s *TestSuite1) TestFilterTruncatewordsHTML(c *C) {
+	ctx := pongo2.Context{"text": `<p>This is a <b>long</b> text with 4.50 numbers</p>`}
+
+	c.Assert(getResult("{{ text|truncatewords_html_ext:4 }}", ctx), Equals, `<p>This is a <b>long...</b></p>`)
+	c.Assert(getResult("{{ text|truncatewords_html_ext:'6, (more)' }}", ctx), Equals, `<p>This is a <b>long</b> text with (more)</p>`)
+	c.Assert(getResult("{{ text|truncatewords_html_ext:'7' }}", ctx), Equals, `<p>This is a <b>long</b> text with 4.50...</p>`)
+	c.Assert(getResult("{{ text|truncatewords_html_ext:'8' }}", ctx), Equals, `<p>This is a <b>long</b> text with 4.50 numbers</p>`)
+	c.Assert(getResult("{{ text|truncatewords_html_ext:0 }}", ctx), Equals, ``)
+}
+
+func (s *TestSuite1) TestFilterTruncateHTMLBuiltins(c *C) {
+	ctx := pongo2.Context{"text": `<p>This is a <b>long</b> text with 4.50 numbers</p>`}
+
+	// the pongo2 builtins are left as they are
+	c.Assert(getResult("{{ text|truncatechars_html:10 }}", ctx), Equals, `<p>This is...</p>`)
+	c.Assert(getResult("{{ text|truncatechars_html:0 }}", ctx), Equals, `...`)
+	c.Assert(getResult("{{ text|truncatewords_html:4 }}", ctx), Equals, `<p>This is a <b>long</b> text ...</p>`)
+	c.Assert(getResult("{{ text|truncatewords_html:7 }}", ctx), Equals, `<p>This is a <b>long</b> text with 4.50 ...</p>`)
+	c.Assert(getResult("{{ text|truncatewords_html:0 }}", ctx), Equals, `...`)
+}
+
+func (s *TestSuite1) TestTruncateHTMLTokenizer(c *C) {
+	// void and self-closing elements are not closed again
+	c.Assert(truncateHTMLAt(`<p>one<br>two<img src="a.png"/>three<hr></p>`, 7, "…"), Equals, `<p>one<br>two<img src="a.png"/>t…</p>`)
+	c.Assert(truncateHTMLAt(`<div><x-icon /> text</div>`, 3, ""), Equals, `<div><x-icon /> te</div>`)
+
+	// ">" inside quoted attributes
+	c.Assert(truncateHTMLAt(`<a title="a > b" href="/">link text</a>`, 4, "…"), Equals, `<a title="a > b" href="/">link…</a>`)
+
+	// comments and doctype are kept as they are and are not counted
+	c.Assert(truncateHTMLAt(`<!DOCTYPE html><!-- <b>not a tag</b> --><p>Hello world</p>`, 5, ""), Equals,
+		`<!DOCTYPE html><!-- <b>not a tag</b> --><p>Hello</p>`)
+
+	// entities count as one character and are never cut
+	c.Assert(truncateHTMLAt(`<p>Tom &amp; Jerry &copy2023</p>`, 5, "…"), Equals, `<p>Tom &amp;…</p>`)
+	c.Assert(truncateHTMLAt(`<p>Tom &amp; Jerry &copy2023</p>`, 14, "…"), Equals, `<p>Tom &amp; Jerry &copy2…</p>`)
+	c.Assert(string(htmlTextRunes(`a&lt;b&#65;&#x42;&ampc`)), Equals, `a<bAB&c`)
+
+	// script content is not counted
+	c.Assert(truncateHTMLAt(`<script>var a = "<b>";</script><p>text</p>`, 2, ""), Equals, `<script>var a = "<b>";</script><p>te</p>`)
+
+	// malformed HTML
+	c.Assert(truncateHTMLAt(`<div><p><b>bold</p> more text</div>`, 6, ""), Equals, `<div><p><b>bold</p> m</div>`)
+
+	c.Assert(getResult("{{ text|truncatechars_html_ext:'8,…' }}", pongo2.Context{"text": `<p>a&nbsp;&amp;&nbsp;b<br>c d e f</p>`}),
+		Equals, `<p>a&nbsp;&amp;&nbsp;b<br>c …</p>`)
+}
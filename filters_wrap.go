@@ -0,0 +1,186 @@
+package pongo2addons
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+	"github.com/rivo/uniseg"
+)
+
+const (
+	// wordwrapDefaultWidth is the line width in display columns.
+	wordwrapDefaultWidth = 80
+	// wordwrapEmailWidth is the line width of the email mode, RFC 3676 recommends 72 for flowed text.
+	wordwrapEmailWidth = 72
+)
+
+// filterWordwrapWidth wraps the text at word boundaries so that lines fit the width in display columns:
+//
+//	{{ text|wordwrap_width:"60" }}
+//	{{ text|wordwrap_width:"72,email" }}
+//
+// East Asian wide characters take two columns. Words longer than the width are not broken.
+// The email mode writes format=flowed text (RFC 3676): soft line breaks end with a space,
+// quoted lines keep their ">" prefix, the signature separator "-- " is kept and lines
+// starting with "From " or a space are space-stuffed.
+func filterWordwrapWidth(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	email := strings.TrimSpace(pongoParam(param, 1).String()) == "email"
+
+	width := wordwrapDefaultWidth
+	if email {
+		width = wordwrapEmailWidth
+	}
+	if param.IsNumber() {
+		width = param.Integer()
+	} else if strings.TrimSpace(pongoParam(param, 0).String()) != "" {
+		width = pongoParam(param, 0).Integer()
+	}
+	width = max(width, 1)
+
+	text := strings.ReplaceAll(in.String(), "\r\n", "\n")
+
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		if !email {
+			out = append(out, wrapLine(line, "", width, false)...)
+			continue
+		}
+
+		line = strings.TrimRight(line, " \t")
+		if line == "--" {
+			// the signature separator keeps its trailing space
+			out = append(out, "-- ")
+			continue
+		}
+
+		depth := 0
+		for depth < len(line) && line[depth] == '>' {
+			depth++
+		}
+		prefix := ""
+		if depth > 0 {
+			prefix = strings.Repeat(">", depth) + " "
+			line = strings.TrimPrefix(line[depth:], " ")
+		} else if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "From ") {
+			line = " " + line
+		}
+
+		out = append(out, wrapLine(line, prefix, width, true)...)
+	}
+
+	return pongo2.AsValue(strings.Join(out, "\n")), nil
+}
+
+// wrapLine wraps a single line greedily. The leading whitespace of the line is kept on the
+// first row, the prefix starts every row. In the flowed mode all rows but the last end with
+// a space, which marks a soft line break, and unquoted rows starting with "From " or ">"
+// are space-stuffed.
+func wrapLine(line string, prefix string, width int, flowed bool) []string {
+	indent := line[:len(line)-len(strings.TrimLeftFunc(line, unicode.IsSpace))]
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return []string{strings.TrimRightFunc(prefix, unicode.IsSpace) + indent}
+	}
+
+	var rows []string
+	row := prefix + indent + words[0]
+	rowWidth := uniseg.StringWidth(row)
+	for _, w := range words[1:] {
+		wordWidth := uniseg.StringWidth(w)
+		if rowWidth+1+wordWidth <= width {
+			row += " " + w
+			rowWidth += 1 + wordWidth
+			continue
+		}
+
+		if flowed {
+			row += " "
+		}
+		rows = append(rows, row)
+		row = prefix + w
+		if flowed && prefix == "" && (w == "From" || strings.HasPrefix(w, ">")) {
+			// a row of its own must not look like a quote or an mbox separator
+			row = " " + row
+		}
+		rowWidth = uniseg.StringWidth(row)
+	}
+
+	return append(rows, row)
+}
+
+// filterSoftHyphenate inserts soft hyphens into the long words of the text (plain or HTML)
+// where the hyphenation dictionary of the language allows it:
+//
+//	{{ text|soft_hyphenate }}
+//	{{ text|soft_hyphenate:"de,wbr" }}
+//	{{ post.Body|soft_hyphenate:"en,html" }}
+//
+// The breaks are "&shy;" by default or "<wbr>" with the "wbr" option. The input is escaped as text;
+// with "html", or if the input is a safe value, its tags are kept.
+func filterSoftHyphenate(in *pongo2.Value, param *pongo2.Value) (*pongo2.Value, *pongo2.Error) {
+	lang := strings.TrimSpace(pongoParam(param, 0).String())
+	if lang == "" {
+		lang = defaultHyphenationLanguage
+	}
+	breaker := "&shy;"
+	// tags are kept only with the html option or in safe values, like the output of other filters
+	markup := isSafeValue(in)
+	options := strings.Split(param.String(), ",")
+	for _, option := range options[min(len(options), 1):] {
+		switch strings.TrimSpace(option) {
+		case "wbr":
+			breaker = "<wbr>"
+		case "html":
+			markup = true
+		}
+	}
+
+	var b strings.Builder
+	var text []rune
+
+	flush := func() {
+		start := -1
+		for i := 0; i <= len(text); i++ {
+			if i < len(text) && (unicode.IsLetter(text[i]) || unicode.IsMark(text[i])) {
+				if start < 0 {
+					start = i
+				}
+				continue
+			}
+			if start >= 0 {
+				word := text[start:i]
+				last := 0
+				for _, p := range hyphenationPoints(word, lang) {
+					b.WriteString(html.EscapeString(string(word[last:p])))
+					b.WriteString(breaker)
+					last = p
+				}
+				b.WriteString(html.EscapeString(string(word[last:])))
+				start = -1
+			}
+			if i < len(text) {
+				b.WriteString(html.EscapeString(string(text[i])))
+			}
+		}
+		text = text[:0]
+	}
+
+	if !markup {
+		text = []rune(in.String())
+		flush()
+		return pongo2.AsSafeValue(b.String()), nil
+	}
+
+	walkHTML(in.String(), func(raw string, _ string, _ []string) {
+		flush()
+		b.WriteString(raw)
+	}, func(r rune, _ string) bool {
+		text = append(text, r)
+		return true
+	})
+	flush()
+
+	return pongo2.AsSafeValue(b.String()), nil
+}
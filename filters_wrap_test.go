@@ -0,0 +1,53 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestFilterWordwrapWidth(c *C) {
+	ctx := pongo2.Context{"text": "The quick brown fox jumps over the lazy dog and keeps running\n\n  indented line that is long enough"}
+	c.Assert(getResult("{{ text|wordwrap_width:'20' }}", ctx), Equals,
+		"The quick brown fox\njumps over the lazy\ndog and keeps\nrunning\n\n  indented line that\nis long enough")
+
+	// wide characters take two columns, long words are not broken
+	ctx = pongo2.Context{"text": "日本語 テキスト 日本 語の"}
+	c.Assert(getResult("{{ text|wordwrap_width:10 }}", ctx), Equals, "日本語\nテキスト\n日本 語の")
+	c.Assert(getResult("{{ text|wordwrap_width:'3' }}", ctx), Equals, "日本語\nテキスト\n日本\n語の")
+
+	// format=flowed email text
+	ctx = pongo2.Context{"text": "Hello there, this is a long line of the message\r\n" +
+		"> quoted text that is rather long too\n>>deeper\nFrom here on  \n--\nBob"}
+	c.Assert(getResult("{% autoescape off %}{{ text|wordwrap_width:'20,email' }}{% endautoescape %}", ctx), Equals,
+		"Hello there, this is \na long line of the \nmessage\n> quoted text that \n> is rather long too\n>> deeper\n From here on\n-- \nBob")
+	c.Assert(getResult("{{ 'short'|wordwrap_width:',email' }}", ctx), Equals, "short")
+
+	// wrapped rows are space-stuffed too
+	ctx["text"] = "Hello there friends From now on"
+	c.Assert(getResult("{% autoescape off %}{{ text|wordwrap_width:'20,email' }}{% endautoescape %}", ctx), Equals,
+		"Hello there friends \n From now on")
+	ctx["text"] = "Hello there friends >not quoted"
+	c.Assert(getResult("{% autoescape off %}{{ text|wordwrap_width:'20,email' }}{% endautoescape %}", ctx), Equals,
+		"Hello there friends \n >not quoted")
+}
+
+func (s *TestSuite1) TestFilterSoftHyphenate(c *C) {
+	ctx := pongo2.Context{"text": "Happiness and transformation of the <b>balloon</b>: a project & a table"}
+	c.Assert(getResult("{{ text|soft_hyphenate:'en,html' }}", ctx), Equals,
+		"Hap&shy;pi&shy;ness and trans&shy;forma&shy;tion of the <b>bal&shy;loon</b>: a pro&shy;ject &amp; a ta&shy;ble")
+	ctx["safe"] = pongo2.AsSafeValue("<i>balloon</i>")
+	c.Assert(getResult("{{ safe|soft_hyphenate }}", ctx), Equals, "<i>bal&shy;loon</i>")
+
+	// plain input is escaped with its tags
+	c.Assert(getResult("{{ text|soft_hyphenate }}", ctx), Equals,
+		"Hap&shy;pi&shy;ness and trans&shy;forma&shy;tion of the &lt;b&gt;bal&shy;loon&lt;/b&gt;: a pro&shy;ject &amp; a ta&shy;ble")
+	ctx["xss"] = "<img src=x onerror=alert(1)>hello"
+	c.Assert(getResult("{{ xss|soft_hyphenate }}", ctx), Equals, "&lt;img src=x oner&shy;ror=alert(1)&gt;hello")
+
+	ctx = pongo2.Context{"text": "Interconnection preview"}
+	c.Assert(getResult("{{ text|soft_hyphenate:'en,wbr' }}", ctx), Equals, "Inter<wbr>con<wbr>nec<wbr>tion pre<wbr>view")
+
+	// unknown languages use English
+	c.Assert(getResult("{{ 'balloon'|soft_hyphenate:'xx' }}", ctx), Equals, "bal&shy;loon")
+}
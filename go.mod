@@ -3,16 +3,22 @@ module github.com/iostrovok/pongo2-addons
 go 1.18
 
 require (
+	github.com/BurntSushi/toml v1.4.0
+	github.com/alecthomas/chroma v0.10.0
 	github.com/extemporalgenome/slug v0.0.0-20150414033109-0320c85e32e0
 	github.com/flosch/go-humanize v0.0.0-20140728123800-3ba51eabe506
 	github.com/flosch/pongo2/v6 v6.0.0
 	github.com/iostrovok/check v0.0.14
+	github.com/rivo/uniseg v0.4.7
 	github.com/russross/blackfriday/v2 v2.1.0
+	golang.org/x/net v0.17.0
+	golang.org/x/text v0.13.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
+	github.com/dlclark/regexp2 v1.4.0 // indirect
 	github.com/iostrovok/go-convert v0.1.11 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
-	golang.org/x/text v0.5.0 // indirect
 )
@@ -0,0 +1,204 @@
+package pongo2addons
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const (
+	// defaultHyphenationLanguage is used when soft_hyphenate gets no language or an unknown one.
+	defaultHyphenationLanguage = "en"
+
+	// hyphenationLeftMin and hyphenationRightMin are the shortest parts of a word kept
+	// before the first and after the last hyphen, as TeX does for English.
+	hyphenationLeftMin  = 2
+	hyphenationRightMin = 3
+)
+
+// hyphenationDictionary holds the Liang patterns and the exceptions of a language.
+type hyphenationDictionary struct {
+	// patterns maps the letters of a pattern to its inter-letter values, e.g. "hy3ph" is
+	// stored as "hyph" => [0 0 3 0 0].
+	patterns map[string][]int
+	// maxLen is the length of the longest pattern in runes.
+	maxLen int
+	// exceptions maps a lowercased word to the positions it is hyphenated at.
+	exceptions map[string][]int
+}
+
+var (
+	hyphenationMu sync.RWMutex
+
+	hyphenationDictionaries = map[string]*hyphenationDictionary{}
+)
+
+func init() {
+	// a small set of English patterns for the common suffixes, prefixes and double consonants;
+	// load the full TeX dictionary with LoadHyphenationPatterns for a better result
+	RegisterHyphenationPatterns("en",
+		"1tion", "1sion", "1ment", "1ness", "1less", "1able", "1ible", "1ture",
+		".pre1", ".dis1", ".mis1", ".non1", ".over1", ".under1", ".inter1", ".trans1", ".anti1",
+		"b1b", "c1c", "d1d", "f1f", "g1g", "l1l", "m1m", "n1n", "p1p", "r1r", "s1s", "t1t", "z1z")
+	RegisterHyphenationExceptions("en", "as-so-ciate", "as-so-ciates", "pro-ject", "pro-jects", "ta-ble", "ta-bles")
+}
+
+func hyphenationDictionaryFor(lang string) *hyphenationDictionary {
+	lang = strings.ToLower(lang)
+	d := hyphenationDictionaries[lang]
+	if d == nil {
+		d = &hyphenationDictionary{patterns: map[string][]int{}, exceptions: map[string][]int{}}
+		hyphenationDictionaries[lang] = d
+	}
+
+	return d
+}
+
+// RegisterHyphenationPatterns adds Liang (TeX) hyphenation patterns like "hy3ph" or ".un1"
+// to the language used by the soft_hyphenate filter.
+func RegisterHyphenationPatterns(lang string, patterns ...string) {
+	hyphenationMu.Lock()
+	defer hyphenationMu.Unlock()
+
+	d := hyphenationDictionaryFor(lang)
+	for _, p := range patterns {
+		var letters []rune
+		values := []int{0}
+		for _, r := range strings.ToLower(p) {
+			if r >= '0' && r <= '9' {
+				values[len(values)-1] = int(r - '0')
+				continue
+			}
+			letters = append(letters, r)
+			values = append(values, 0)
+		}
+		if len(letters) == 0 {
+			continue
+		}
+
+		d.patterns[string(letters)] = values
+		d.maxLen = max(d.maxLen, len(letters))
+	}
+}
+
+// RegisterHyphenationExceptions adds words hyphenated explicitly, like "ta-ble", to the language.
+func RegisterHyphenationExceptions(lang string, words ...string) {
+	hyphenationMu.Lock()
+	defer hyphenationMu.Unlock()
+
+	d := hyphenationDictionaryFor(lang)
+	for _, w := range words {
+		var points []int
+		n := 0
+		for _, r := range strings.ToLower(w) {
+			if r == '-' {
+				points = append(points, n)
+				continue
+			}
+			n++
+		}
+		d.exceptions[strings.ReplaceAll(strings.ToLower(w), "-", "")] = points
+	}
+}
+
+// LoadHyphenationPatterns reads a TeX hyphenation file (hyph-*.tex in UTF-8) with its
+// \patterns{...} and \hyphenation{...} groups into the language.
+func LoadHyphenationPatterns(lang string, r io.Reader) error {
+	var patterns, exceptions []string
+	var group *[]string
+
+	found := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexRune(line, '%'); i >= 0 {
+			line = line[:i]
+		}
+
+		for _, field := range strings.Fields(line) {
+			switch {
+			case strings.HasPrefix(field, `\patterns{`):
+				group, field, found = &patterns, strings.TrimPrefix(field, `\patterns{`), true
+			case strings.HasPrefix(field, `\hyphenation{`):
+				group, field, found = &exceptions, strings.TrimPrefix(field, `\hyphenation{`), true
+			}
+			if group == nil {
+				continue
+			}
+
+			closed := strings.HasSuffix(field, "}")
+			if field = strings.TrimSuffix(field, "}"); field != "" {
+				*group = append(*group, field)
+			}
+			if closed {
+				group = nil
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("no \\patterns or \\hyphenation group found")
+	}
+
+	RegisterHyphenationPatterns(lang, patterns...)
+	RegisterHyphenationExceptions(lang, exceptions...)
+
+	return nil
+}
+
+// hyphenationPoints returns the rune offsets inside the word where it may be hyphenated.
+func hyphenationPoints(word []rune, lang string) []int {
+	if len(word) < hyphenationLeftMin+hyphenationRightMin {
+		return nil
+	}
+
+	hyphenationMu.RLock()
+	defer hyphenationMu.RUnlock()
+
+	d, ok := hyphenationDictionaries[strings.ToLower(lang)]
+	if !ok {
+		d = hyphenationDictionaries[defaultHyphenationLanguage]
+	}
+	if d == nil {
+		return nil
+	}
+
+	lower := []rune(strings.ToLower(string(word)))
+	if len(lower) != len(word) {
+		return nil
+	}
+	if points, ok := d.exceptions[string(lower)]; ok {
+		return points
+	}
+
+	// Liang's algorithm: every matching pattern raises the values between the letters,
+	// odd values allow a hyphen
+	padded := append(append([]rune{'.'}, lower...), '.')
+	values := make([]int, len(padded)+1)
+	for i := range padded {
+		for j := i + 1; j <= len(padded) && j-i <= d.maxLen; j++ {
+			p, ok := d.patterns[string(padded[i:j])]
+			if !ok {
+				continue
+			}
+			for k, v := range p {
+				values[i+k] = max(values[i+k], v)
+			}
+		}
+	}
+
+	var points []int
+	for i := hyphenationLeftMin; i <= len(word)-hyphenationRightMin; i++ {
+		// the value between word[i-1] and word[i] sits after the leading "."
+		if values[i+1]%2 == 1 && unicode.IsLetter(word[i-1]) && unicode.IsLetter(word[i]) {
+			points = append(points, i)
+		}
+	}
+
+	return points
+}
@@ -0,0 +1,34 @@
+package pongo2addons
+
+import (
+	"strings"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestHyphenationPoints(c *C) {
+	// the patterns of the example in Liang's thesis
+	RegisterHyphenationPatterns("x-liang", "hy3ph", "he2n", "hena4", "hen5at", "1na", "n2at", "1tio", "2io", "o2i")
+	c.Assert(hyphenationPoints([]rune("hyphenation"), "x-liang"), DeepEquals, []int{2, 6})
+	c.Assert(hyphenationPoints([]rune("Hyphenation"), "x-liang"), DeepEquals, []int{2, 6})
+
+	// too short words and the exceptions
+	c.Assert(hyphenationPoints([]rune("hyph"), "x-liang"), IsNil)
+	RegisterHyphenationExceptions("x-liang", "hyph-e-na-tion")
+	c.Assert(hyphenationPoints([]rune("hyphenation"), "x-liang"), DeepEquals, []int{4, 5, 7})
+}
+
+func (s *TestSuite1) TestLoadHyphenationPatterns(c *C) {
+	tex := `% hyph-x.tex
+\patterns{ % the patterns
+.ab1 c1d
+e1f}
+\hyphenation{
+ab-cd-ef
+}`
+	c.Assert(LoadHyphenationPatterns("x-tex", strings.NewReader(tex)), IsNil)
+	c.Assert(hyphenationPoints([]rune("abcdefgh"), "x-tex"), DeepEquals, []int{2, 3, 5})
+	c.Assert(hyphenationPoints([]rune("abcdef"), "x-tex"), DeepEquals, []int{2, 4})
+
+	c.Assert(LoadHyphenationPatterns("x-tex", strings.NewReader("no patterns")), NotNil)
+}
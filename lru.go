@@ -0,0 +1,116 @@
+package pongo2addons
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruCache is a small least-recently-used cache, safe for concurrent use. It is bounded by
+// the number of items, or by their total size when the items are added with addSized.
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List
+
+	// maxBytes bounds the total size of the items, bytes is their current size.
+	maxBytes int
+	bytes    int
+}
+
+type lruEntry struct {
+	key   string
+	value interface{}
+	size  int
+}
+
+func newLRUCache(size int) *lruCache {
+	return &lruCache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// newSizedLRUCache returns a cache bounded by the total size of the items in bytes.
+func newSizedLRUCache(maxBytes int) *lruCache {
+	c := newLRUCache(0)
+	c.maxBytes = maxBytes
+
+	return c
+}
+
+func (c *lruCache) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(el)
+	return el.Value.(*lruEntry).value, true
+}
+
+func (c *lruCache) add(key string, value interface{}) {
+	c.addSized(key, value, 0)
+}
+
+// addSized adds the item of the size in bytes, items larger than the whole cache are not added.
+func (c *lruCache) addSized(key string, value interface{}, size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.maxBytes > 0 && size > c.maxBytes {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+		return
+	}
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		c.bytes += size - entry.size
+		entry.value, entry.size = value, size
+		c.order.MoveToFront(el)
+	} else {
+		c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, size: size})
+		c.bytes += size
+	}
+
+	for c.size > 0 && c.order.Len() > c.size || c.maxBytes > 0 && c.bytes > c.maxBytes {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *lruCache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *lruCache) count() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// usedBytes returns the total size of the items.
+func (c *lruCache) usedBytes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bytes
+}
+
+func (c *lruCache) removeElement(el *list.Element) {
+	entry := el.Value.(*lruEntry)
+	c.order.Remove(el)
+	delete(c.items, entry.key)
+	c.bytes -= entry.size
+}
@@ -0,0 +1,266 @@
+package pongo2addons
+
+import (
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// defaultSentenceLanguage is used when truncatesentences gets no language or an unknown one.
+const defaultSentenceLanguage = "en"
+
+var (
+	sentenceAbbreviationsMu sync.RWMutex
+
+	// sentenceAbbreviations are lowercased words which are followed by a period
+	// without ending the sentence, keyed by language.
+	sentenceAbbreviations = map[string]map[string]bool{}
+)
+
+func init() {
+	RegisterSentenceAbbreviations("en", "mr", "mrs", "ms", "dr", "prof", "sr", "jr", "st", "vs", "e.g", "i.e",
+		"etc", "inc", "ltd", "co", "corp", "fig", "no", "approx", "dept", "est", "jan", "feb", "mar", "apr",
+		"jun", "jul", "aug", "sep", "sept", "oct", "nov", "dec", "a.m", "p.m")
+	RegisterSentenceAbbreviations("de", "z.b", "bzw", "usw", "d.h", "ca", "dr", "nr", "str", "vgl", "u.a",
+		"evtl", "ggf", "bspw", "prof", "hr", "fr", "s", "u.s.w")
+	RegisterSentenceAbbreviations("fr", "m", "mm", "mme", "mlle", "dr", "pr", "p.ex", "etc", "cf", "av",
+		"bd", "env", "n°")
+	RegisterSentenceAbbreviations("es", "sr", "sra", "srta", "dr", "dra", "ud", "uds", "p.ej", "etc", "pág",
+		"núm", "av", "aprox")
+	RegisterSentenceAbbreviations("ru", "т.е", "т.д", "т.п", "т.к", "г", "гг", "ул", "д", "им", "др", "проф",
+		"стр", "см", "руб", "коп", "тыс", "млн", "млрд")
+}
+
+// RegisterSentenceAbbreviations adds abbreviations to the language list used by the truncatesentences filters.
+// Abbreviations are given without the final period, e.g. "e.g" or "Dr".
+func RegisterSentenceAbbreviations(lang string, abbreviations ...string) {
+	sentenceAbbreviationsMu.Lock()
+	defer sentenceAbbreviationsMu.Unlock()
+
+	lang = strings.ToLower(lang)
+	if sentenceAbbreviations[lang] == nil {
+		sentenceAbbreviations[lang] = map[string]bool{}
+	}
+	for _, a := range abbreviations {
+		sentenceAbbreviations[lang][strings.ToLower(a)] = true
+	}
+}
+
+func isSentenceAbbreviation(lang, word string) bool {
+	sentenceAbbreviationsMu.RLock()
+	defer sentenceAbbreviationsMu.RUnlock()
+
+	list, ok := sentenceAbbreviations[lang]
+	if !ok {
+		list = sentenceAbbreviations[defaultSentenceLanguage]
+	}
+
+	return list[word]
+}
+
+// isSentenceTerminator reports the characters that always end a sentence (STerm in UAX #29).
+func isSentenceTerminator(r rune) bool {
+	switch r {
+	case '!', '?', '‼', '⁇', '⁈', '⁉', '؟', '।', '॥', '։', '።', '፧', '፨', '。', '！', '？', '｡':
+		return true
+	}
+
+	return false
+}
+
+// isFullStopWithoutSpace reports the terminators of scripts which don't separate sentences by spaces.
+func isFullStopWithoutSpace(r rune) bool {
+	switch r {
+	case '。', '！', '？', '｡':
+		return true
+	}
+
+	return false
+}
+
+// isSentenceSeparator reports the paragraph separators, they always end a sentence.
+func isSentenceSeparator(r rune) bool {
+	return r == '\n' || r == '\u2029' || r == '\u0085'
+}
+
+// isSentenceClose reports the quotes and brackets which belong to the end of the sentence.
+func isSentenceClose(r rune) bool {
+	return r == '"' || r == '\'' || unicode.In(r, unicode.Pe, unicode.Pf) || r == '»' || r == '›'
+}
+
+// isSentenceOpen reports the quotes, brackets and inverted marks which may start a sentence.
+func isSentenceOpen(r rune) bool {
+	return r == '"' || r == '\'' || r == '¿' || r == '¡' || unicode.In(r, unicode.Ps, unicode.Pi) || r == '«' || r == '‹'
+}
+
+// sentenceBoundaries splits text into sentences following the UAX #29 sentence boundary rules
+// and the language abbreviation list. It returns the rune offsets where the sentences end;
+// the text after the last terminator is a sentence as well. Parts without letters and digits
+// are not sentences, they are joined to the next one.
+func sentenceBoundaries(text []rune, lang string) []int {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+
+	var bounds []int
+	start := 0
+
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+
+		end := -1
+		switch {
+		case isSentenceSeparator(r):
+			end = i + 1
+		case isSentenceTerminator(r) || r == '.':
+			k := i + 1
+			for k < len(text) && (isSentenceTerminator(text[k]) || text[k] == '.') {
+				k++
+			}
+			j := k
+			for j < len(text) && isSentenceClose(text[j]) {
+				j++
+			}
+
+			periods, spaceless := true, false
+			for _, t := range text[i:k] {
+				periods = periods && t == '.'
+				spaceless = spaceless || isFullStopWithoutSpace(t)
+			}
+
+			switch {
+			case j < len(text) && !unicode.IsSpace(text[j]) && !spaceless:
+				// "4.50", "example.com", "Yahoo!Inc"
+			case periods && nextWordIsLower(text, j):
+				// UAX #29, SB8: "etc. and so on"
+			case k == i+1 && r == '.' && isSentenceAbbreviationBefore(text, i, lang):
+				// "Dr. Smith", "J. Smith", "U.S. Army"
+			default:
+				end = j
+			}
+
+			if end < 0 {
+				i = j - 1
+				continue
+			}
+		default:
+			continue
+		}
+
+		if hasWordRune(text[start:end]) {
+			bounds = append(bounds, end)
+			start = end
+		}
+		i = end - 1
+	}
+
+	if start < len(text) && hasWordRune(text[start:]) {
+		bounds = append(bounds, len(text))
+	}
+
+	return bounds
+}
+
+// sentencesCut returns the number of runes of text taken by its first count sentences.
+// Both truncatesentences filters cut the text here, so they always agree on the sentences.
+func sentencesCut(text []rune, count int, lang string) (int, bool) {
+	bounds := sentenceBoundaries(text, lang)
+	if count >= len(bounds) {
+		return len(text), false
+	}
+
+	return bounds[count-1], true
+}
+
+func hasWordRune(text []rune) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isSentenceAbbreviationBefore checks the word before the period at idx: initials like "J."
+// and dotted abbreviations like "U.S." are never ends of sentences.
+func isSentenceAbbreviationBefore(text []rune, idx int, lang string) bool {
+	start := idx
+	for start > 0 && (unicode.IsLetter(text[start-1]) || unicode.IsDigit(text[start-1]) || text[start-1] == '.') {
+		start--
+	}
+
+	word := strings.ToLower(string(text[start:idx]))
+	if word == "" {
+		return false
+	}
+	if len([]rune(word)) == 1 && unicode.IsUpper(text[start]) {
+		return true
+	}
+	if strings.Contains(word, ".") && !strings.Contains(word, "..") {
+		short := true
+		for _, part := range strings.Split(word, ".") {
+			short = short && len([]rune(part)) <= 2
+		}
+		if short {
+			return true
+		}
+	}
+
+	return isSentenceAbbreviation(lang, word)
+}
+
+// nextWordIsLower reports whether the text after a period goes on with a lowercase letter (UAX #29, SB8).
+func nextWordIsLower(text []rune, idx int) bool {
+	for idx < len(text) && (unicode.IsSpace(text[idx]) || isSentenceOpen(text[idx])) {
+		idx++
+	}
+
+	return idx < len(text) && unicode.IsLower(text[idx])
+}
+
+// htmlBlockElements are the elements which separate sentences in HTML.
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "header": true, "hr": true,
+	"li": true, "main": true, "nav": true, "ol": true, "p": true, "pre": true, "section": true, "table": true,
+	"td": true, "th": true, "tr": true, "ul": true,
+}
+
+// htmlSentenceText returns the visible text of the HTML value with "\n" at the boundaries
+// of block elements. offsets maps every rune of it (and its end) to the number of visible
+// characters of the value before it.
+func htmlSentenceText(value string) ([]rune, []int) {
+	var text []rune
+	var offsets []int
+
+	count := 0
+	walkHTML(value, func(_ string, tag string, _ []string) {
+		if htmlBlockElements[tag] && len(text) > 0 && text[len(text)-1] != '\n' {
+			text = append(text, '\n')
+			offsets = append(offsets, count)
+		}
+	}, func(r rune, _ string) bool {
+		text = append(text, r)
+		offsets = append(offsets, count)
+		count++
+		return true
+	})
+
+	return text, append(offsets, count)
+}
+
+// sentencesParams parses the "count[,language]" parameter of the truncatesentences filters.
+func sentencesParams(param *pongo2.Value) (int, string) {
+	if param.IsNumber() {
+		return param.Integer(), defaultSentenceLanguage
+	}
+
+	lang := strings.TrimSpace(pongoParam(param, 1).String())
+	if lang == "" {
+		lang = defaultSentenceLanguage
+	}
+
+	return pongoParam(param, 0).Integer(), lang
+}
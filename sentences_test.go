@@ -0,0 +1,112 @@
+package pongo2addons
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func splitSentences(text, lang string) []string {
+	runes := []rune(text)
+	var out []string
+	start := 0
+	for _, end := range sentenceBoundaries(runes, lang) {
+		out = append(out, string(runes[start:end]))
+		start = end
+	}
+
+	return out
+}
+
+// sentenceCorpus is shared by the segmenter and both truncatesentences filters.
+var sentenceCorpus = []struct {
+	lang      string
+	text      string
+	sentences []string
+}{
+	{"en", "Dr. Smith arrived. He was late.", []string{"Dr. Smith arrived.", " He was late."}},
+	{"en", "Use e.g. tea or i.e. water. Fine!", []string{"Use e.g. tea or i.e. water.", " Fine!"}},
+	{"en", "J. R. R. Tolkien wrote it. The U.S. Army read it.", []string{"J. R. R. Tolkien wrote it.", " The U.S. Army read it."}},
+	{"en", "It costs 4.50 now. See example.com for more", []string{"It costs 4.50 now.", " See example.com for more"}},
+	{"en", `He said "Stop!" Then he left... Nobody knew why?! Really.`,
+		[]string{`He said "Stop!"`, ` Then he left...`, ` Nobody knew why?!`, ` Really.`}},
+	{"en", "Wait... what happened. Line one\nLine two", []string{"Wait... what happened.", " Line one\n", "Line two"}},
+	{"en", "... !!! Start here. ", []string{"... !!! Start here."}},
+	{"en", "This is a first sentence with a 4.50 number. The second one is even more fun! Isn't it? Last sentence, okay.",
+		[]string{"This is a first sentence with a 4.50 number.", " The second one is even more fun!", " Isn't it?", " Last sentence, okay."}},
+	{"en", "Tom & Jerry <3 cheese. So do I.", []string{"Tom & Jerry <3 cheese.", " So do I."}},
+
+	// CJK full stops don't need a space
+	{"ja", "今日は晴れです。明日は雨です！本当？はい", []string{"今日は晴れです。", "明日は雨です！", "本当？", "はい"}},
+	{"ja", "「行こう。」と言った。", []string{"「行こう。」", "と言った。"}},
+
+	// Spanish inverted marks
+	{"es", "¿Qué tal? ¡Muy bien! Hola Sr. García. Adiós.", []string{"¿Qué tal?", " ¡Muy bien!", " Hola Sr. García.", " Adiós."}},
+
+	// language abbreviations
+	{"de", "Wir trinken z.B. Tee. Das ist gut.", []string{"Wir trinken z.B. Tee.", " Das ist gut."}},
+	{"ru", "Он живёт на ул. Ленина. Это далеко.", []string{"Он живёт на ул. Ленина.", " Это далеко."}},
+	{"en", "Он живёт на ул. Ленина. Это далеко.", []string{"Он живёт на ул.", " Ленина.", " Это далеко."}},
+}
+
+// corpusHTML marks up the plain text, every second word becomes bold.
+func corpusHTML(text string) string {
+	words := regexp.MustCompile(`\S+`)
+	i := 0
+	return "<p>" + words.ReplaceAllStringFunc(html.EscapeString(text), func(w string) string {
+		i++
+		if i%2 == 0 {
+			return "<b>" + w + "</b>"
+		}
+		return w
+	}) + "</p>"
+}
+
+func (s *TestSuite1) TestSentenceBoundaries(c *C) {
+	for _, t := range sentenceCorpus {
+		c.Assert(splitSentences(t.text, t.lang), DeepEquals, t.sentences, Commentf("%s: %q", t.lang, t.text))
+	}
+
+	RegisterSentenceAbbreviations("en", "Approx2")
+	c.Assert(splitSentences("It is approx2. Ten.", "en"), DeepEquals, []string{"It is approx2. Ten."})
+}
+
+func (s *TestSuite1) TestSentencesPlainAndHTMLAgree(c *C) {
+	for _, t := range sentenceCorpus {
+		markup := corpusHTML(t.text)
+
+		for n := 1; n <= len(t.sentences)+1; n++ {
+			param := pongo2.AsValue(fmt.Sprintf("%d,%s", n, t.lang))
+			comment := Commentf("%s: %q, %d sentences", t.lang, t.text, n)
+
+			plain, err := filterTruncatesentences(pongo2.AsValue(t.text), param)
+			c.Assert(err, IsNil)
+			c.Assert(plain.String(), Equals, strings.TrimSpace(strings.Join(t.sentences[:min(n, len(t.sentences))], "")), comment)
+
+			rich, err := filterTruncatesentencesHTML(pongo2.AsValue(markup), param)
+			c.Assert(err, IsNil)
+			c.Assert(strings.TrimSpace(string(htmlTextRunes(rich.String()))), Equals, plain.String(), comment)
+		}
+	}
+}
+
+func (s *TestSuite1) TestFilterTruncatesentencesLanguages(c *C) {
+	ctx := pongo2.Context{"text": "Dr. Smith arrived at 10 a.m. Today. He was late! Was he?"}
+	c.Assert(getResult("{{ text|truncatesentences:1 }}", ctx), Equals, "Dr. Smith arrived at 10 a.m. Today.")
+	c.Assert(getResult("{{ text|truncatesentences:'2' }}", ctx), Equals, "Dr. Smith arrived at 10 a.m. Today. He was late!")
+	c.Assert(getResult("{{ text|truncatesentences:'10' }}", ctx), Equals, "Dr. Smith arrived at 10 a.m. Today. He was late! Was he?")
+
+	ctx = pongo2.Context{"text": "Это т.е. пример. Второе предложение. Третье."}
+	c.Assert(getResult("{{ text|truncatesentences:'1,ru' }}", ctx), Equals, "Это т.е. пример.")
+
+	ctx = pongo2.Context{"text": "<p>今日は<b>晴れ</b>です。明日は雨です！</p>"}
+	c.Assert(getResult("{{ text|truncatesentences_html:'1,ja' }}", ctx), Equals, "<p>今日は<b>晴れ</b>です。</p>")
+
+	ctx = pongo2.Context{"text": "<p>Dr. <i>Smith</i> arrived.</p><p>He was late</p>"}
+	c.Assert(getResult("{{ text|truncatesentences_html:1 }}", ctx), Equals, "<p>Dr. <i>Smith</i> arrived.</p>")
+}
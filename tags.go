@@ -0,0 +1,20 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+)
+
+func init() {
+	// renders the page links of the paginate filter result
+	pongo2.RegisterTag("paginator", tagPaginatorParser)
+
+	// variables
+	pongo2.RegisterTag("capture", tagCaptureParser)
+	pongo2.RegisterTag("set_default", tagSetDefaultParser)
+
+	// markup
+	pongo2.RegisterTag("minify_html", tagMinifyHTMLParser)
+
+	// caching
+	pongo2.RegisterTag("cache", tagCacheParser)
+}
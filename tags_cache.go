@@ -0,0 +1,250 @@
+package pongo2addons
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// fragmentCacheDefaultSize is the number of fragments kept by the default in-process cache.
+const fragmentCacheDefaultSize = 1000
+
+// FragmentCache stores the fragments rendered by the cache tag, implementations must be safe for concurrent use.
+type FragmentCache interface {
+	// Get returns the fragment stored with the key unless it is expired.
+	Get(key string) (string, bool)
+	// Set stores the fragment for the ttl, 0 means it never expires.
+	Set(key string, fragment string, ttl time.Duration)
+	// Delete removes the fragment.
+	Delete(key string)
+}
+
+// fragmentCacheNow is the clock of LRUFragmentCache, tests replace it.
+var fragmentCacheNow = time.Now
+
+type fragmentCacheEntry struct {
+	fragment string
+	expires  time.Time
+}
+
+// LRUFragmentCache is an in-process FragmentCache keeping the recently used fragments.
+type LRUFragmentCache struct {
+	mu    sync.Mutex
+	size  int
+	cache *lruCache
+}
+
+// NewLRUFragmentCache returns a cache keeping at most size fragments, 0 means no limit.
+func NewLRUFragmentCache(size int) *LRUFragmentCache {
+	return &LRUFragmentCache{size: size, cache: newLRUCache(size)}
+}
+
+// Get returns the fragment unless it is expired, expired fragments are removed.
+func (c *LRUFragmentCache) Get(key string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	value, ok := c.cache.get(key)
+	if !ok {
+		return "", false
+	}
+
+	entry := value.(fragmentCacheEntry)
+	if !entry.expires.IsZero() && !fragmentCacheNow().Before(entry.expires) {
+		c.cache.remove(key)
+		return "", false
+	}
+
+	return entry.fragment, true
+}
+
+// Set stores the fragment for the ttl, 0 or less means it never expires.
+func (c *LRUFragmentCache) Set(key string, fragment string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := fragmentCacheEntry{fragment: fragment}
+	if ttl > 0 {
+		entry.expires = fragmentCacheNow().Add(ttl)
+	}
+	c.cache.add(key, entry)
+}
+
+// Delete removes the fragment.
+func (c *LRUFragmentCache) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache.remove(key)
+}
+
+// Len returns the number of stored fragments, the expired ones included until they are looked up.
+func (c *LRUFragmentCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.cache.count()
+}
+
+// Purge removes all fragments.
+func (c *LRUFragmentCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.cache = newLRUCache(c.size)
+}
+
+var (
+	fragmentCacheMu sync.RWMutex
+
+	fragmentCache FragmentCache = NewLRUFragmentCache(fragmentCacheDefaultSize)
+)
+
+// SetFragmentCache replaces the cache of the cache tag, by default it is an in-process
+// LRUFragmentCache of 1000 fragments. A nil cache disables caching.
+func SetFragmentCache(cache FragmentCache) {
+	fragmentCacheMu.Lock()
+	defer fragmentCacheMu.Unlock()
+
+	fragmentCache = cache
+}
+
+func currentFragmentCache() FragmentCache {
+	fragmentCacheMu.RLock()
+	defer fragmentCacheMu.RUnlock()
+
+	return fragmentCache
+}
+
+// FragmentCacheKey returns the key of the fragment rendered by {% cache ttl name vary... %} with
+// the values of the vary-on expressions. The values are compared by their template output.
+func FragmentCacheKey(name string, vary ...interface{}) string {
+	h := sha256.New()
+	for _, v := range vary {
+		s := pongo2.AsValue(v).String()
+		h.Write([]byte(strconv.Itoa(len(s)) + ":" + s))
+	}
+
+	return "pongo2addons:fragment:" + name + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// InvalidateFragment removes the fragment of the name rendered with the vary-on values from the cache:
+//
+//	pongo2addons.InvalidateFragment("sidebar", user.ID)
+func InvalidateFragment(name string, vary ...interface{}) {
+	if cache := currentFragmentCache(); cache != nil {
+		cache.Delete(FragmentCacheKey(name, vary...))
+	}
+}
+
+// cacheTimeout converts the timeout of the cache tag in seconds, numbers and numeric strings are accepted.
+func cacheTimeout(ttl *pongo2.Value) (time.Duration, error) {
+	seconds := ttl.Float()
+	if !ttl.IsNumber() {
+		var err error
+		if seconds, err = strconv.ParseFloat(strings.TrimSpace(ttl.String()), 64); err != nil {
+			return 0, fmt.Errorf("cache timeout %q is not a number of seconds", ttl.String())
+		}
+	}
+	if seconds < 0 {
+		return 0, errors.New("cache timeout is negative")
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+type tagCacheNode struct {
+	position *pongo2.Token
+	ttl      pongo2.IEvaluator
+	name     pongo2.IEvaluator
+	vary     []pongo2.IEvaluator
+	wrapper  *pongo2.NodeWrapper
+}
+
+// Execute writes the cached fragment, or renders and caches it.
+func (node *tagCacheNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	ttl, err := node.ttl.Evaluate(ctx)
+	if err != nil {
+		return err
+	}
+	timeout, timeoutErr := cacheTimeout(ttl)
+	if timeoutErr != nil {
+		return ctx.OrigError(timeoutErr, node.position)
+	}
+
+	name, err := node.name.Evaluate(ctx)
+	if err != nil {
+		return err
+	}
+
+	vary := make([]interface{}, len(node.vary))
+	for i, expression := range node.vary {
+		value, err := expression.Evaluate(ctx)
+		if err != nil {
+			return err
+		}
+		vary[i] = value.Interface()
+	}
+
+	cache := currentFragmentCache()
+	key := FragmentCacheKey(name.String(), vary...)
+	if cache != nil {
+		if fragment, ok := cache.Get(key); ok {
+			_, _ = writer.WriteString(fragment)
+			return nil
+		}
+	}
+
+	var b bytes.Buffer
+	if err := node.wrapper.Execute(ctx, &b); err != nil {
+		return err
+	}
+	if cache != nil {
+		cache.Set(key, b.String(), timeout)
+	}
+	_, _ = writer.Write(b.Bytes())
+
+	return nil
+}
+
+// tagCacheParser parses {% cache ttl name [vary...] %}...{% endcache %}. The block is rendered once
+// and taken from the cache for ttl seconds (0 means until it is removed), separately for
+// every combination of the values of the vary-on expressions:
+//
+//	{% cache 300 "sidebar" user.ID %}{{ sidebar|markdown }}{% endcache %}
+func tagCacheParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	node := &tagCacheNode{position: start}
+
+	var expressions []pongo2.IEvaluator
+	for arguments.Remaining() > 0 {
+		expression, err := arguments.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, expression)
+	}
+	if len(expressions) < 2 {
+		return nil, arguments.Error("Expected the timeout and the fragment name.", nil)
+	}
+	node.ttl, node.name, node.vary = expressions[0], expressions[1], expressions[2:]
+
+	wrapper, endArguments, err := doc.WrapUntilTag("endcache")
+	if err != nil {
+		return nil, err
+	}
+	node.wrapper = wrapper
+
+	if endArguments.Remaining() > 0 {
+		return nil, endArguments.Error("Arguments not allowed here.", nil)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,82 @@
+package pongo2addons
+
+import (
+	"time"
+
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestTagCache(c *C) {
+	cache := NewLRUFragmentCache(2)
+	SetFragmentCache(cache)
+	defer SetFragmentCache(NewLRUFragmentCache(fragmentCacheDefaultSize))
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fragmentCacheNow = func() time.Time { return now }
+	defer func() { fragmentCacheNow = time.Now }()
+
+	calls := 0
+	ctx := pongo2.Context{
+		"render": func() int {
+			calls++
+			return calls
+		},
+		"user": map[string]interface{}{"id": 7},
+	}
+
+	tpl := `{% cache 300 "sidebar" user.id %}<aside>{{ render() }}</aside>{% endcache %}`
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>1</aside>")
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>1</aside>")
+
+	// other vary-on values are cached separately
+	ctx["user"] = map[string]interface{}{"id": 8}
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>2</aside>")
+	ctx["user"] = map[string]interface{}{"id": 7}
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>1</aside>")
+
+	// expiry
+	now = now.Add(300 * time.Second)
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>3</aside>")
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>3</aside>")
+
+	// invalidation from Go
+	InvalidateFragment("sidebar", 7)
+	c.Assert(getResult(tpl, ctx), Equals, "<aside>4</aside>")
+
+	// no expiry, the least recently used fragment is evicted
+	c.Assert(getResult(`{% cache 0 "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "5")
+	now = now.Add(1000 * time.Hour)
+	c.Assert(getResult(`{% cache "0" "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "5")
+	c.Assert(cache.Len(), Equals, 2)
+	c.Assert(getResult(`{% cache 0 "b" %}{{ render() }}{% endcache %}`, ctx), Equals, "6")
+	c.Assert(getResult(`{% cache 0 "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "5")
+	c.Assert(cache.Len(), Equals, 2)
+
+	cache.Purge()
+	c.Assert(cache.Len(), Equals, 0)
+	c.Assert(getResult(`{% cache 0 "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "7")
+
+	// a nil cache disables caching
+	SetFragmentCache(nil)
+	c.Assert(getResult(`{% cache 0 "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "8")
+	c.Assert(getResult(`{% cache 0 "a" %}{{ render() }}{% endcache %}`, ctx), Equals, "9")
+
+	// errors
+	c.Assert(getResult(`{% cache "soon" "a" %}x{% endcache %}`, ctx), Equals, "")
+	c.Assert(getResult(`{% cache -1 "a" %}x{% endcache %}`, ctx), Equals, "")
+	for tpl, msg := range map[string]string{
+		`{% cache 300 %}x{% endcache %}`:       ".*Expected the timeout and the fragment name.*",
+		`{% cache 300 "a" %}x{% endcache a %}`: ".*Arguments not allowed here.*",
+	} {
+		_, err := pongo2.FromString(tpl)
+		c.Assert(err, ErrorMatches, msg)
+	}
+}
+
+func (s *TestSuite1) TestFragmentCacheKey(c *C) {
+	c.Assert(FragmentCacheKey("a", 1, "b"), Equals, FragmentCacheKey("a", "1", "b"))
+	c.Assert(FragmentCacheKey("a", "1:b"), Not(Equals), FragmentCacheKey("a", "1", "b"))
+	c.Assert(FragmentCacheKey("a"), Not(Equals), FragmentCacheKey("b"))
+}
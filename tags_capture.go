@@ -0,0 +1,55 @@
+package pongo2addons
+
+import (
+	"bytes"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+type tagCaptureNode struct {
+	name    string
+	wrapper *pongo2.NodeWrapper
+}
+
+// Execute renders the block and keeps the output in the variable instead of writing it.
+func (node *tagCaptureNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	var b bytes.Buffer
+	if err := node.wrapper.Execute(ctx, &b); err != nil {
+		return err
+	}
+
+	// the output is escaped already while rendering
+	ctx.Private[node.name] = pongo2.AsSafeValue(b.String())
+
+	return nil
+}
+
+// tagCaptureParser parses {% capture name %}...{% endcapture %}, the rendered block is
+// available as the variable afterwards:
+//
+//	{% capture title %}{{ user.Name }} – {{ site }}{% endcapture %}<title>{{ title }}</title>
+func tagCaptureParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	node := &tagCaptureNode{}
+
+	name := arguments.MatchType(pongo2.TokenIdentifier)
+	if name == nil {
+		return nil, arguments.Error("Expected an identifier.", nil)
+	}
+	node.name = name.Val
+
+	if arguments.Remaining() > 0 {
+		return nil, arguments.Error("Malformed capture-tag arguments.", nil)
+	}
+
+	wrapper, endArguments, err := doc.WrapUntilTag("endcapture")
+	if err != nil {
+		return nil, err
+	}
+	node.wrapper = wrapper
+
+	if endArguments.Remaining() > 0 {
+		return nil, endArguments.Error("Arguments not allowed here.", nil)
+	}
+
+	return node, nil
+}
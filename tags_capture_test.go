@@ -0,0 +1,29 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestTagCapture(c *C) {
+	ctx := pongo2.Context{"name": "Tom & Jerry", "site": "Cartoons", "numbers": []int{1, 2, 3}}
+
+	c.Assert(getResult("{% capture title %}{{ name }} – {{ site }}{% endcapture %}<title>{{ title }}</title>", ctx),
+		Equals, "<title>Tom &amp; Jerry – Cartoons</title>")
+	c.Assert(getResult("{% capture x %}{% for i in numbers %}{{ i }}{% endfor %}{% endcapture %}{{ x|length }}:{{ x }}", ctx),
+		Equals, "3:123")
+	c.Assert(getResult("{% capture outer %}[{% capture inner %}in{% endcapture %}{{ inner|upper }}]{% endcapture %}{{ outer }}{{ inner }}", ctx),
+		Equals, "[IN]in")
+
+	// errors
+	for tpl, msg := range map[string]string{
+		"{% capture %}x{% endcapture %}":     ".*Expected an identifier.*",
+		"{% capture a b %}x{% endcapture %}": ".*Malformed capture-tag arguments.*",
+		"{% capture a %}x{% endcapture a %}": ".*Arguments not allowed here.*",
+		"{% capture a %}x":                   ".*endcapture.*",
+	} {
+		_, err := pongo2.FromString(tpl)
+		c.Assert(err, ErrorMatches, msg)
+	}
+}
@@ -0,0 +1,197 @@
+package pongo2addons
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/flosch/pongo2/v6"
+	"golang.org/x/net/html"
+)
+
+// minifyBlockElements don't render the whitespace around them, it is removed.
+var minifyBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true, "br": true, "caption": true,
+	"col": true, "colgroup": true, "dd": true, "details": true, "dialog": true, "div": true, "dl": true, "dt": true,
+	"fieldset": true, "figcaption": true, "figure": true, "footer": true, "form": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "head": true, "header": true, "hr": true, "html": true,
+	"li": true, "link": true, "main": true, "meta": true, "nav": true, "ol": true, "optgroup": true, "option": true,
+	"p": true, "pre": true, "script": true, "section": true, "style": true, "summary": true, "table": true,
+	"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true, "title": true, "tr": true, "ul": true,
+}
+
+// minifyPreservedElements keep their content as it is.
+var minifyPreservedElements = map[string]bool{
+	"pre": true, "script": true, "style": true, "textarea": true,
+}
+
+type minifyToken struct {
+	kind html.TokenType
+	raw  string
+	tag  string
+}
+
+// isBlock reports whether the token is a tag of a block element or the beginning or the end of the document.
+func (t *minifyToken) isBlock() bool {
+	return t == nil || minifyBlockElements[t.tag]
+}
+
+// collapseHTMLSpaces replaces every run of HTML whitespace with a single space, other spaces like
+// &nbsp; are kept.
+func collapseHTMLSpaces(s string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\r', '\f':
+			space = true
+		default:
+			if space {
+				b.WriteByte(' ')
+				space = false
+			}
+			b.WriteByte(s[i])
+		}
+	}
+	if space {
+		b.WriteByte(' ')
+	}
+
+	return b.String()
+}
+
+// minifyTag collapses the whitespace between the attributes of the tag, quoted values are kept.
+// There is no whitespace left around "=" and before the end of the tag.
+func minifyTag(raw string) string {
+	var b strings.Builder
+	quote, last := byte(0), byte(0)
+	space := false
+	for i := 0; i < len(raw); i++ {
+		c := raw[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+			continue
+		case c == '"' || c == '\'':
+			quote = c
+		}
+		if space && c != '>' && c != '=' && last != '=' && !(c == '/' && i+1 < len(raw) && raw[i+1] == '>') {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteByte(c)
+		last = c
+	}
+
+	return b.String()
+}
+
+// minifyHTML removes comments (but conditional ones) and collapses whitespace. Whitespace next to
+// block elements is removed, between inline content it is a single space. The content of
+// pre, textarea, script and style is kept.
+func minifyHTML(s string) string {
+	var tokens []*minifyToken
+	z := html.NewTokenizer(strings.NewReader(s))
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		t := &minifyToken{kind: tt, raw: string(z.Raw())}
+		if tt == html.StartTagToken || tt == html.EndTagToken || tt == html.SelfClosingTagToken {
+			name, _ := z.TagName()
+			t.tag = string(name)
+		}
+		tokens = append(tokens, t)
+	}
+
+	var b strings.Builder
+	preserved := 0
+	for i, t := range tokens {
+		switch t.kind {
+		case html.CommentToken:
+			if strings.HasPrefix(t.raw, "<!--[if") || strings.HasPrefix(t.raw, "<![endif]") {
+				b.WriteString(t.raw)
+			}
+		case html.TextToken:
+			if preserved > 0 {
+				b.WriteString(t.raw)
+				continue
+			}
+			text := collapseHTMLSpaces(t.raw)
+			var prev, next *minifyToken
+			if i > 0 {
+				prev = tokens[i-1]
+			}
+			if i+1 < len(tokens) {
+				next = tokens[i+1]
+			}
+			if prev.isBlock() {
+				text = strings.TrimLeft(text, " ")
+			}
+			if next.isBlock() {
+				text = strings.TrimRight(text, " ")
+			}
+			b.WriteString(text)
+		case html.StartTagToken:
+			if minifyPreservedElements[t.tag] {
+				preserved++
+			}
+			b.WriteString(minifyTag(t.raw))
+		case html.EndTagToken:
+			if minifyPreservedElements[t.tag] && preserved > 0 {
+				preserved--
+			}
+			b.WriteString(minifyTag(t.raw))
+		case html.SelfClosingTagToken:
+			b.WriteString(minifyTag(t.raw))
+		default:
+			b.WriteString(t.raw)
+		}
+	}
+
+	return b.String()
+}
+
+type tagMinifyHTMLNode struct {
+	wrapper *pongo2.NodeWrapper
+}
+
+// Execute renders the block and writes it minified.
+func (node *tagMinifyHTMLNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	var b bytes.Buffer
+	if err := node.wrapper.Execute(ctx, &b); err != nil {
+		return err
+	}
+
+	_, _ = writer.WriteString(minifyHTML(b.String()))
+
+	return nil
+}
+
+// tagMinifyHTMLParser parses {% minify_html %}...{% endminify_html %}. Unlike {% spaceless %} it
+// also collapses whitespace in text and inside tags and removes comments:
+//
+//	{% minify_html %}
+//	<ul>
+//	    <li><a href="/">Home</a></li>  <!-- todo -->
+//	</ul>
+//	{% endminify_html %}
+func tagMinifyHTMLParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	if arguments.Remaining() > 0 {
+		return nil, arguments.Error("Malformed minify_html-tag arguments.", nil)
+	}
+
+	wrapper, endArguments, err := doc.WrapUntilTag("endminify_html")
+	if err != nil {
+		return nil, err
+	}
+	if endArguments.Remaining() > 0 {
+		return nil, endArguments.Error("Arguments not allowed here.", nil)
+	}
+
+	return &tagMinifyHTMLNode{wrapper: wrapper}, nil
+}
@@ -0,0 +1,35 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestTagMinifyHTML(c *C) {
+	ctx := pongo2.Context{"items": []string{"Home", "About us"}}
+
+	c.Assert(getResult(`{% minify_html %}
+<ul class="menu"
+    id = "main">
+    {% for item in items %}
+    <li>  <a href="/{{ item|slugify }}"  >{{ item }}</a>  </li>  <!-- todo -->
+    {% endfor %}
+</ul>
+{% endminify_html %}`, ctx), Equals, `<ul class="menu" id="main"><li><a href="/home">Home</a></li><li><a href="/about-us">About us</a></li></ul>`)
+
+	// inline elements keep a single space, &nbsp; is kept
+	c.Assert(getResult("{% minify_html %}<p>\n  Hello,\n  <b>big</b>   <i>world</i>&nbsp; !\n</p>{% endminify_html %}", ctx),
+		Equals, "<p>Hello, <b>big</b> <i>world</i>&nbsp; !</p>")
+
+	// preformatted content, quoted attributes and conditional comments are kept
+	c.Assert(getResult("{% minify_html %}<pre>  a\n   b </pre> <textarea>  x  </textarea>\n"+
+		"<script>  if (a  <  b) {}  </script> <img  alt=\"a  b\"  /> <!--[if IE]>ie<![endif]-->{% endminify_html %}", ctx),
+		Equals, "<pre>  a\n   b </pre><textarea>  x  </textarea><script>  if (a  <  b) {}  </script><img alt=\"a  b\"/> <!--[if IE]>ie<![endif]-->")
+
+	// errors
+	_, err := pongo2.FromString("{% minify_html x %}a{% endminify_html %}")
+	c.Assert(err, ErrorMatches, ".*Malformed minify_html-tag arguments.*")
+	_, err = pongo2.FromString("{% minify_html %}a{% endminify_html x %}")
+	c.Assert(err, ErrorMatches, ".*Arguments not allowed here.*")
+}
@@ -0,0 +1,129 @@
+package pongo2addons
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/flosch/pongo2/v6"
+)
+
+// paginatorDefaultURL is the link format of the pages, "%d" is replaced with the page number.
+const paginatorDefaultURL = "?page=%d"
+
+// paginatorDefaultTemplate renders the page links, it gets the Pagination as "pagination"
+// and the "url" function returning the link of a page number.
+const paginatorDefaultTemplate = `<nav class="pagination"><ul>` +
+	`{% if pagination.HasPrev %}<li class="prev"><a href="{{ url(pagination.Prev) }}" rel="prev">&laquo;</a></li>{% endif %}` +
+	`{% for p in pagination.Pages %}` +
+	`{% if p.Ellipsis %}<li class="ellipsis">&hellip;</li>` +
+	`{% elif p.Current %}<li class="current"><span aria-current="page">{{ p.Number }}</span></li>` +
+	`{% else %}<li><a href="{{ url(p.Number) }}">{{ p.Number }}</a></li>{% endif %}` +
+	`{% endfor %}` +
+	`{% if pagination.HasNext %}<li class="next"><a href="{{ url(pagination.Next) }}" rel="next">&raquo;</a></li>{% endif %}` +
+	`</ul></nav>`
+
+var (
+	paginatorTemplateMu sync.RWMutex
+
+	paginatorTemplate = pongo2.Must(pongo2.FromString(paginatorDefaultTemplate))
+)
+
+// SetPaginatorTemplate replaces the HTML snippet rendered by the paginator tag. The snippet
+// gets the Pagination as "pagination" and the "url" function returning the link of a page number:
+//
+//	{% for p in pagination.Pages %}<a href="{{ url(p.Number) }}">{{ p.Number }}</a>{% endfor %}
+func SetPaginatorTemplate(src string) error {
+	tpl, err := pongo2.FromString(src)
+	if err != nil {
+		return err
+	}
+
+	paginatorTemplateMu.Lock()
+	defer paginatorTemplateMu.Unlock()
+	paginatorTemplate = tpl
+
+	return nil
+}
+
+type tagPaginatorNode struct {
+	position   *pongo2.Token
+	pagination pongo2.IEvaluator
+	url        pongo2.IEvaluator
+}
+
+// Execute renders the paginator snippet for the pagination.
+func (node *tagPaginatorNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	value, err := node.pagination.Evaluate(ctx)
+	if err != nil {
+		return err
+	}
+
+	var pagination *Pagination
+	switch p := value.Interface().(type) {
+	case *Pagination:
+		pagination = p
+	case Pagination:
+		pagination = &p
+	}
+	if pagination == nil {
+		return ctx.OrigError(errors.New("paginator argument is not a paginate filter result"), node.position)
+	}
+
+	format := paginatorDefaultURL
+	if node.url != nil {
+		url, err := node.url.Evaluate(ctx)
+		if err != nil {
+			return err
+		}
+		format = url.String()
+	}
+
+	paginatorTemplateMu.RLock()
+	tpl := paginatorTemplate
+	paginatorTemplateMu.RUnlock()
+
+	out, tplErr := tpl.Execute(pongo2.Context{
+		"pagination": pagination,
+		"url": func(page int) string {
+			if !strings.Contains(format, "%d") {
+				return format + strconv.Itoa(page)
+			}
+			return strings.ReplaceAll(format, "%d", strconv.Itoa(page))
+		},
+	})
+	if tplErr != nil {
+		return ctx.OrigError(tplErr, node.position)
+	}
+
+	_, _ = writer.WriteString(out)
+
+	return nil
+}
+
+// tagPaginatorParser parses {% paginator pagination ["url format"] %}, the pages are linked to
+// the format with "%d" replaced by the page number, "?page=%d" by default.
+func tagPaginatorParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	node := &tagPaginatorNode{position: start}
+
+	pagination, err := arguments.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	node.pagination = pagination
+
+	if arguments.Remaining() > 0 {
+		url, err := arguments.ParseExpression()
+		if err != nil {
+			return nil, err
+		}
+		node.url = url
+	}
+
+	if arguments.Remaining() > 0 {
+		return nil, arguments.Error("Malformed paginator-tag arguments.", nil)
+	}
+
+	return node, nil
+}
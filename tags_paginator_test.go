@@ -0,0 +1,33 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestTagPaginator(c *C) {
+	ctx := pongo2.Context{"total": 50, "url": "/list?sort=name&page=%d"}
+
+	c.Assert(getResult("{% paginator total|paginate:'2,10' %}", ctx), Equals, `<nav class="pagination"><ul>`+
+		`<li class="prev"><a href="?page=1" rel="prev">&laquo;</a></li>`+
+		`<li><a href="?page=1">1</a></li>`+
+		`<li class="current"><span aria-current="page">2</span></li>`+
+		`<li><a href="?page=3">3</a></li><li><a href="?page=4">4</a></li><li><a href="?page=5">5</a></li>`+
+		`<li class="next"><a href="?page=3" rel="next">&raquo;</a></li>`+
+		`</ul></nav>`)
+
+	c.Assert(SetPaginatorTemplate(`{% for p in pagination.Pages %}{% if p.Ellipsis %}…{% else %}<a href="{{ url(p.Number) }}">{{ p.Number }}</a>{% endif %}{% endfor %}`), IsNil)
+	defer func() {
+		c.Assert(SetPaginatorTemplate(paginatorDefaultTemplate), IsNil)
+	}()
+
+	c.Assert(getResult("{% paginator total|paginate:'1,10,1,1' url %}", ctx), Equals,
+		`<a href="/list?sort=name&amp;page=1">1</a><a href="/list?sort=name&amp;page=2">2</a>…<a href="/list?sort=name&amp;page=5">5</a>`)
+	c.Assert(getResult("{% paginator total|paginate:'1,25' '/page/' %}", ctx), Equals,
+		`<a href="/page/1">1</a><a href="/page/2">2</a>`)
+
+	// errors
+	c.Assert(SetPaginatorTemplate("{% if %}"), NotNil)
+	c.Assert(getResult("{% paginator total %}", ctx), Equals, "")
+}
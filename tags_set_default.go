@@ -0,0 +1,68 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+)
+
+type tagSetDefaultNode struct {
+	name       string
+	expression pongo2.IEvaluator
+}
+
+// Execute sets the variable if it is not defined or nil, the expression is evaluated only then.
+func (node *tagSetDefaultNode) Execute(ctx *pongo2.ExecutionContext, writer pongo2.TemplateWriter) *pongo2.Error {
+	value, ok := ctx.Private[node.name]
+	if !ok {
+		value, ok = ctx.Public[node.name]
+	}
+	if ok && !contextValue(value).IsNil() {
+		return nil
+	}
+
+	evaluated, err := node.expression.Evaluate(ctx)
+	if err != nil {
+		return err
+	}
+	ctx.Private[node.name] = evaluated
+
+	return nil
+}
+
+// contextValue wraps a context value, values set by tags are *pongo2.Value already.
+func contextValue(value interface{}) *pongo2.Value {
+	if v, ok := value.(*pongo2.Value); ok {
+		return v
+	}
+
+	return pongo2.AsValue(value)
+}
+
+// tagSetDefaultParser parses {% set_default name = expression %}, which is {% set %} for
+// variables which are not defined or nil:
+//
+//	{% set_default page_title = "Home" %}
+func tagSetDefaultParser(doc *pongo2.Parser, start *pongo2.Token, arguments *pongo2.Parser) (pongo2.INodeTag, *pongo2.Error) {
+	node := &tagSetDefaultNode{}
+
+	name := arguments.MatchType(pongo2.TokenIdentifier)
+	if name == nil {
+		return nil, arguments.Error("Expected an identifier.", nil)
+	}
+	node.name = name.Val
+
+	if arguments.Match(pongo2.TokenSymbol, "=") == nil {
+		return nil, arguments.Error("Expected '='.", nil)
+	}
+
+	expression, err := arguments.ParseExpression()
+	if err != nil {
+		return nil, err
+	}
+	node.expression = expression
+
+	if arguments.Remaining() > 0 {
+		return nil, arguments.Error("Malformed set_default-tag arguments.", nil)
+	}
+
+	return node, nil
+}
@@ -0,0 +1,30 @@
+package pongo2addons
+
+import (
+	"github.com/flosch/pongo2/v6"
+
+	. "github.com/iostrovok/check"
+)
+
+func (s *TestSuite1) TestTagSetDefault(c *C) {
+	ctx := pongo2.Context{"title": "About", "empty": "", "missing": nil}
+
+	c.Assert(getResult(`{% set_default title = "Home" %}{{ title }}`, ctx), Equals, "About")
+	c.Assert(getResult(`{% set_default other = "Home" %}{{ other }}`, ctx), Equals, "Home")
+	c.Assert(getResult(`{% set_default missing = title|upper %}{{ missing }}`, ctx), Equals, "ABOUT")
+	c.Assert(getResult(`{% set_default empty = "Home" %}[{{ empty }}]`, ctx), Equals, "[]")
+	c.Assert(getResult(`{% set x = 1 %}{% set_default x = 2 %}{% set_default y = x + 1 %}{{ x }}{{ y }}`, ctx), Equals, "12")
+
+	// the expression is not evaluated for defined variables
+	c.Assert(getResult(`{% set_default title = title|regex_match:"(" %}{{ title }}`, ctx), Equals, "About")
+
+	// errors
+	for tpl, msg := range map[string]string{
+		`{% set_default "x" = 1 %}`: ".*Expected an identifier.*",
+		`{% set_default x 1 %}`:     ".*Expected '='.*",
+		`{% set_default x = 1 2 %}`: ".*Malformed set_default-tag arguments.*",
+	} {
+		_, err := pongo2.FromString(tpl)
+		c.Assert(err, ErrorMatches, msg)
+	}
+}